@@ -0,0 +1,213 @@
+package apperror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+
+	"github.com/kenztech/go-api-starter/models/api"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Code identifies an error for clients. Codes are part of the API contract:
+// never rename one, add a new code instead.
+type Code string
+
+const (
+	CodeInvalidRequest         Code = "invalid_request"
+	CodeValidationFailed       Code = "validation_failed"
+	CodeInvalidID              Code = "invalid_id"
+	CodeUnauthorized           Code = "unauthorized"
+	CodeInvalidCredentials     Code = "invalid_credentials"
+	CodeInvalidResetToken      Code = "invalid_reset_token"
+	CodeInvalidSetupToken      Code = "invalid_setup_token"
+	CodeForbidden              Code = "forbidden"
+	CodeAccountInactive        Code = "account_inactive"
+	CodePasswordChangeRequired Code = "password_change_required"
+	CodeImpersonationForbidden Code = "impersonation_forbidden"
+	CodeCannotImpersonateSelf  Code = "cannot_impersonate_self"
+	CodeCannotImpersonateAdmin Code = "cannot_impersonate_admin"
+	CodeNotImpersonating       Code = "not_impersonating"
+	CodeNotFound               Code = "not_found"
+	CodeUserNotFound           Code = "user_not_found"
+	CodeConflict               Code = "conflict"
+	CodeUserExists             Code = "user_exists"
+	CodeVersionConflict        Code = "version_conflict"
+	CodeSetupCompleted         Code = "setup_completed"
+	CodePreconditionFailed     Code = "precondition_failed"
+	CodePreconditionRequired   Code = "precondition_required"
+	CodeInvalidIdempotencyKey  Code = "invalid_idempotency_key"
+	CodeIdempotencyInFlight    Code = "idempotency_key_in_use"
+	CodeIdempotencyKeyReused   Code = "idempotency_key_reused"
+	CodeOrganizationRequired   Code = "organization_required"
+	CodeOrganizationNotFound   Code = "organization_not_found"
+	CodeOrganizationExists     Code = "organization_exists"
+	CodeNotMember              Code = "not_a_member"
+	CodeRateLimited            Code = "rate_limited"
+	CodeRequestCanceled        Code = "request_canceled"
+	CodeInternal               Code = "internal_error"
+	CodeTimeout                Code = "timeout"
+)
+
+// StatusClientClosedRequest is reported when the client went away before
+// the response was written. Nobody reads it, but it shows up in logs and
+// metrics instead of a misleading 500.
+const StatusClientClosedRequest = 499
+
+// AppError is an error meant for API clients. Status, Code, Message and
+// Fields are sent in the response; Err is the internal cause, only logged.
+type AppError struct {
+	Status  int
+	Code    Code
+	Message string
+	Fields  []api.FieldError
+	Err     error
+
+	// stack is where an internal error was created, for the logs
+	stack []uintptr
+}
+
+func New(status int, code Code, message string) *AppError {
+	return &AppError{Status: status, Code: code, Message: message}
+}
+
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return string(e.Code) + ": " + e.Message + ": " + e.Err.Error()
+	}
+	return string(e.Code) + ": " + e.Message
+}
+
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches errors with the same code, so errors.Is works against the
+// predefined errors below even after Wrap or WithMessage.
+func (e *AppError) Is(target error) bool {
+	t, ok := target.(*AppError)
+	return ok && t.Code == e.Code
+}
+
+// Wrap returns a copy of the error carrying err as its internal cause
+func (e *AppError) Wrap(err error) *AppError {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+// WithMessage returns a copy of the error with another client message
+func (e *AppError) WithMessage(message string) *AppError {
+	c := *e
+	c.Message = message
+	return &c
+}
+
+var (
+	ErrInvalidBody            = New(http.StatusBadRequest, CodeInvalidRequest, "invalid request body")
+	ErrInvalidID              = New(http.StatusBadRequest, CodeInvalidID, "Invalid id")
+	ErrUnauthorized           = New(http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+	ErrInvalidCredentials     = New(http.StatusUnauthorized, CodeInvalidCredentials, "Invalid credentials")
+	ErrForbidden              = New(http.StatusForbidden, CodeForbidden, "Forbidden")
+	ErrAccountInactive        = New(http.StatusForbidden, CodeAccountInactive, "Account is not active")
+	ErrPasswordChangeRequired = New(http.StatusForbidden, CodePasswordChangeRequired, "Password change required")
+	ErrNotFound               = New(http.StatusNotFound, CodeNotFound, "Not found")
+	ErrUserNotFound           = New(http.StatusNotFound, CodeUserNotFound, "User not found")
+	ErrConflict               = New(http.StatusConflict, CodeConflict, "Conflict")
+	ErrUserExists             = New(http.StatusConflict, CodeUserExists, "Email or username already in use")
+	ErrVersionConflict        = New(http.StatusConflict, CodeVersionConflict, "The resource was changed by someone else, reload it and try again")
+	ErrPreconditionFailed     = New(http.StatusPreconditionFailed, CodePreconditionFailed, "If-Match does not match the current version, reload it and try again")
+	ErrPreconditionRequired   = New(http.StatusPreconditionRequired, CodePreconditionRequired, "The If-Match header is required")
+	ErrInvalidIdempotencyKey  = New(http.StatusBadRequest, CodeInvalidIdempotencyKey, "Idempotency-Key must be 1 to 255 characters long")
+	ErrIdempotencyInFlight    = New(http.StatusConflict, CodeIdempotencyInFlight, "A request with this Idempotency-Key is still being processed")
+	ErrIdempotencyKeyReused   = New(http.StatusUnprocessableEntity, CodeIdempotencyKeyReused, "This Idempotency-Key was used for a different request")
+	ErrOrganizationRequired   = New(http.StatusBadRequest, CodeOrganizationRequired, "An organization is required: send X-Organization or use the organization's subdomain")
+	ErrOrganizationNotFound   = New(http.StatusNotFound, CodeOrganizationNotFound, "Organization not found")
+	ErrOrganizationExists     = New(http.StatusConflict, CodeOrganizationExists, "Organization slug already in use")
+	ErrNotMember              = New(http.StatusForbidden, CodeNotMember, "You are not a member of this organization")
+	ErrRateLimited            = New(http.StatusTooManyRequests, CodeRateLimited, "Too many requests")
+	ErrRequestCanceled        = New(StatusClientClosedRequest, CodeRequestCanceled, "Request canceled")
+	ErrInternal               = New(http.StatusInternalServerError, CodeInternal, "Internal server error")
+	ErrTimeout                = New(http.StatusServiceUnavailable, CodeTimeout, "The request took too long, please try again")
+)
+
+// Internal wraps an unexpected error and records the caller's stack. Clients
+// only see the generic message.
+func Internal(err error) *AppError {
+	e := ErrInternal.Wrap(err)
+	e.stack = make([]uintptr, 32)
+	e.stack = e.stack[:runtime.Callers(2, e.stack)]
+	return e
+}
+
+// Stack returns the "function file:line" frames where an internal error was
+// created, or nil
+func (e *AppError) Stack() []string {
+	if len(e.stack) == 0 {
+		return nil
+	}
+
+	var stack []string
+	frames := runtime.CallersFrames(e.stack)
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, fmt.Sprintf("%s %s:%d", frame.Function, frame.File, frame.Line))
+		if !more {
+			return stack
+		}
+	}
+}
+
+// Validation reports invalid input field by field
+func Validation(fields []api.FieldError) *AppError {
+	return &AppError{
+		Status:  http.StatusBadRequest,
+		Code:    CodeValidationFailed,
+		Message: "Validation failed",
+		Fields:  fields,
+	}
+}
+
+// From returns err as an AppError. Missing documents become 404s and
+// deadlines 503s; any other error is unexpected and becomes an internal error.
+// Its stack is unknown by now: wrap errors with Internal where they happen
+// to log it.
+func From(err error) *AppError {
+	var appErr *AppError
+	switch {
+	case errors.As(err, &appErr):
+		return appErr
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return ErrNotFound.Wrap(err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrTimeout.Wrap(err)
+	case errors.Is(err, context.Canceled):
+		return ErrRequestCanceled.Wrap(err)
+	}
+	return ErrInternal.Wrap(err)
+}
+
+// CodeForStatus is the generic code used for errors created from a bare
+// status code
+func CodeForStatus(status int) Code {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeInvalidRequest
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const routesFile = "handlers/routes.go"
+
+// generate writes the files of the resource and registers its routes. It
+// refuses to overwrite existing files.
+func generate(s *spec) error {
+	files := []struct {
+		path     string
+		template *template.Template
+	}{
+		{filepath.Join("models", s.Plural+".go"), modelTemplate},
+		{filepath.Join("models", s.Plural+"_migration.go"), migrationTemplate},
+		{filepath.Join("models", "api", s.Plural+".go"), requestTemplate},
+		{filepath.Join("handlers", s.Plural+".go"), handlerTemplate},
+		{filepath.Join("handlers", s.Plural+"_test.go"), testTemplate},
+	}
+
+	for _, f := range files {
+		if _, err := os.Stat(f.path); err == nil {
+			return fmt.Errorf("%s already exists", f.path)
+		}
+	}
+
+	routes, err := addRoutes(s)
+	if err != nil {
+		return err
+	}
+
+	// Render everything before writing, so a template error leaves the tree
+	// untouched.
+	sources := make([][]byte, len(files))
+	for i, f := range files {
+		if sources[i], err = render(f.template, s); err != nil {
+			return fmt.Errorf("generating %s: %w", f.path, err)
+		}
+	}
+
+	for i, f := range files {
+		if err := os.WriteFile(f.path, sources[i], 0o644); err != nil {
+			return err
+		}
+		fmt.Println("Created", f.path)
+	}
+
+	ptrFile := filepath.Join("handlers", "scaffold_test.go")
+	if _, err := os.Stat(ptrFile); errors.Is(err, os.ErrNotExist) {
+		source, err := render(ptrTemplate, s)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(ptrFile, source, 0o644); err != nil {
+			return err
+		}
+		fmt.Println("Created", ptrFile)
+	}
+
+	if err := os.WriteFile(routesFile, routes, 0o644); err != nil {
+		return err
+	}
+	fmt.Println("Updated", routesFile)
+
+	fmt.Printf("\nServed under /api/%s. Adjust the validation rules in models/api/%s.go,\n", s.Route, s.Plural)
+	fmt.Println("grant permissions to roles in handlers/routes.go, then run go test ./... and migrate up.")
+	return nil
+}
+
+// render executes a template and formats the result as Go source
+func render(t *template.Template, s *spec) ([]byte, error) {
+	var b bytes.Buffer
+	if err := t.Execute(&b, s); err != nil {
+		return nil, err
+	}
+	source, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return source, nil
+}
+
+// addRoutes returns routes.go with the resource's routes added at the end of
+// the /api route in InitRoutes
+func addRoutes(s *spec) ([]byte, error) {
+	source, err := os.ReadFile(routesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, routesFile, source, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var api *ast.FuncLit
+	ast.Inspect(file, func(n ast.Node) bool {
+		if fn, ok := n.(*ast.FuncDecl); ok {
+			return fn.Name.Name == "InitRoutes"
+		}
+		call, ok := n.(*ast.CallExpr)
+		if !ok || api != nil || len(call.Args) != 2 {
+			return true
+		}
+		if sel, ok := call.Fun.(*ast.SelectorExpr); !ok || sel.Sel.Name != "Route" {
+			return true
+		}
+		if path, ok := call.Args[0].(*ast.BasicLit); ok && path.Value == strconv.Quote("/api") {
+			api, _ = call.Args[1].(*ast.FuncLit)
+		}
+		return true
+	})
+	if api == nil {
+		return nil, fmt.Errorf("no /api route in InitRoutes in %s", routesFile)
+	}
+	if bytes.Contains(source, []byte(fmt.Sprintf("r.Route(%q", "/"+s.Route))) {
+		return nil, fmt.Errorf("%s already has a /%s route", routesFile, s.Route)
+	}
+
+	var route bytes.Buffer
+	if err := routeTemplate.Execute(&route, s); err != nil {
+		return nil, err
+	}
+
+	// Insert before the line closing the /api route.
+	end := fset.Position(api.Body.Rbrace).Offset
+	end = strings.LastIndexByte(string(source[:end]), '\n') + 1
+	updated := append(append(append([]byte{}, source[:end]...), route.Bytes()...), source[end:]...)
+
+	formatted, err := format.Source(updated)
+	if err != nil {
+		return nil, fmt.Errorf("formatting %s: %w", routesFile, err)
+	}
+	return formatted, nil
+}
@@ -0,0 +1,229 @@
+// Command scaffold generates the code of a new resource: its model, request
+// structs, handler, routes, index migration and tests. Run it from the
+// repository root:
+//
+//	go run ./cmd/scaffold resource Product --fields name:string,price:float
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/kenztech/go-api-starter/models"
+)
+
+const usage = `Usage: go run ./cmd/scaffold resource <Name> --fields <field:type,...>
+
+Generates the model, request structs, handler, routes, index migration and
+tests of a resource served under /api/<names>. Field names are snake_case
+and types are string, int, float, bool or time.
+`
+
+func main() {
+	if err := run(os.Args[1:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) < 2 || args[0] != "resource" {
+		fmt.Fprint(os.Stderr, usage)
+		return errors.New("usage: scaffold resource <Name> --fields <field:type,...>")
+	}
+
+	fs := flag.NewFlagSet("scaffold resource", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fields := fs.String("fields", "", "comma separated field:type pairs")
+	if err := fs.Parse(args[2:]); err != nil {
+		return err
+	}
+
+	module, err := modulePath("go.mod")
+	if err != nil {
+		return fmt.Errorf("run scaffold from the repository root: %w", err)
+	}
+
+	spec, err := newSpec(module, args[1], *fields)
+	if err != nil {
+		return err
+	}
+	for _, m := range models.Migrations() {
+		spec.Version = max(spec.Version, m.Version+1)
+	}
+
+	return generate(spec)
+}
+
+// modulePath reads the module path from go.mod
+func modulePath(file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if path, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+			return strings.Trim(strings.TrimSpace(path), `"`), nil
+		}
+	}
+	return "", fmt.Errorf("no module path in %s", file)
+}
+
+var (
+	namePattern  = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+	fieldPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+)
+
+// reservedFields are maintained by resource.Base
+var reservedFields = []string{"id", "_id", "org_id", "created_at", "updated_at", "deleted_at", "version"}
+
+// initialisms are written in capitals in Go names
+var initialisms = map[string]string{"id": "ID", "url": "URL", "ip": "IP", "api": "API", "http": "HTTP", "json": "JSON", "sku": "SKU"}
+
+// spec describes the resource to generate
+type spec struct {
+	Module  string
+	Name    string // Go type name, e.g. OrderItem
+	Var     string // variable name, e.g. orderItem
+	Plural  string // collection and file name, e.g. order_items
+	Route   string // URL path segment, e.g. order-items
+	Version int    // version of the index migration
+	Fields  []field
+}
+
+type field struct {
+	Name   string // bson and JSON name
+	GoName string
+	Type   string
+
+	CreateRule string
+	UpdateRule string
+
+	// Go expressions for the tests: a valid value, another one, and an
+	// invalid one with the case it illustrates
+	Sample      string
+	Changed     string
+	Invalid     string
+	InvalidCase string
+}
+
+// fieldTypes maps the types of --fields to Go types, validation rules and
+// test values. Every rule needs a message in the i18n catalogs.
+var fieldTypes = map[string]field{
+	"string": {Type: "string", CreateRule: "required", UpdateRule: "omitempty,min=1", Sample: `"example"`, Changed: `"changed"`, Invalid: `""`, InvalidCase: "missing"},
+	"int":    {Type: "int64", CreateRule: "gte=0", UpdateRule: "omitempty,gte=0", Sample: "1", Changed: "2", Invalid: "-1", InvalidCase: "negative"},
+	"float":  {Type: "float64", CreateRule: "gte=0", UpdateRule: "omitempty,gte=0", Sample: "1.5", Changed: "2.5", Invalid: "-1", InvalidCase: "negative"},
+	"bool":   {Type: "bool", Sample: "true", Changed: "false"},
+	"time":   {Type: "time.Time", CreateRule: "required", Sample: "time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)", Changed: "time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)", Invalid: "time.Time{}", InvalidCase: "missing"},
+}
+
+func newSpec(module, name, fields string) (*spec, error) {
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid resource name %q: use a Go type name such as Product", name)
+	}
+
+	words := splitWords(name)
+	plural := append(words[:len(words)-1:len(words)-1], pluralize(words[len(words)-1]))
+	s := &spec{
+		Module: module,
+		Name:   name,
+		Var:    strings.ToLower(name[:1]) + name[1:],
+		Plural: strings.Join(plural, "_"),
+		Route:  strings.Join(plural, "-"),
+	}
+
+	if fields == "" {
+		return nil, errors.New("--fields is required, e.g. --fields name:string,price:float")
+	}
+	seen := map[string]bool{}
+	for _, pair := range strings.Split(fields, ",") {
+		fieldName, fieldType, _ := strings.Cut(strings.TrimSpace(pair), ":")
+		f, ok := fieldTypes[fieldType]
+		switch {
+		case !fieldPattern.MatchString(fieldName):
+			return nil, fmt.Errorf("invalid field name %q: use snake_case", fieldName)
+		case !ok:
+			return nil, fmt.Errorf("invalid type %q for field %s: use string, int, float, bool or time", fieldType, fieldName)
+		case seen[fieldName]:
+			return nil, fmt.Errorf("field %s is given twice", fieldName)
+		}
+		for _, reserved := range reservedFields {
+			if fieldName == reserved {
+				return nil, fmt.Errorf("field %s is already part of resource.Base", fieldName)
+			}
+		}
+		seen[fieldName] = true
+
+		f.Name = fieldName
+		f.GoName = goName(fieldName)
+		s.Fields = append(s.Fields, f)
+	}
+	return s, nil
+}
+
+// splitWords splits a Go name into lower case words, e.g. OrderItem into
+// order and item
+func splitWords(name string) []string {
+	var words []string
+	start := 0
+	for i := 1; i < len(name); i++ {
+		if name[i] >= 'A' && name[i] <= 'Z' && !(name[i-1] >= 'A' && name[i-1] <= 'Z') {
+			words = append(words, strings.ToLower(name[start:i]))
+			start = i
+		}
+	}
+	return append(words, strings.ToLower(name[start:]))
+}
+
+func pluralize(word string) string {
+	switch {
+	case strings.HasSuffix(word, "y") && len(word) > 1 && !strings.ContainsRune("aeiou", rune(word[len(word)-2])):
+		return word[:len(word)-1] + "ies"
+	case strings.HasSuffix(word, "s"), strings.HasSuffix(word, "x"), strings.HasSuffix(word, "z"),
+		strings.HasSuffix(word, "ch"), strings.HasSuffix(word, "sh"):
+		return word + "es"
+	}
+	return word + "s"
+}
+
+// goName turns a snake_case field name into an exported Go name
+func goName(name string) string {
+	var b strings.Builder
+	for _, word := range strings.Split(name, "_") {
+		if word == "" {
+			continue
+		}
+		if initialism, ok := initialisms[word]; ok {
+			b.WriteString(initialism)
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+// Filters lists the fields lists can be filtered on: query parameters are
+// strings, so only string fields compare equal.
+func (s *spec) Filters() []string {
+	var filters []string
+	for _, f := range s.Fields {
+		if f.Type == "string" {
+			filters = append(filters, f.Name)
+		}
+	}
+	return filters
+}
+
+// HasTime tells whether a generated file must import time
+func (s *spec) HasTime() bool {
+	for _, f := range s.Fields {
+		if f.Type == "time.Time" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,212 @@
+package main
+
+import "text/template"
+
+var modelTemplate = template.Must(template.New("model").Parse(`package models
+
+import (
+{{- if .HasTime}}
+	"time"
+{{end}}
+	"{{.Module}}/resource"
+)
+
+// {{.Name}} is stored in the {{.Plural}} collection
+type {{.Name}} struct {
+	resource.Base ` + "`bson:\",inline\"`" + `
+
+{{range .Fields}}	{{.GoName}} {{.Type}} ` + "`bson:\"{{.Name}}\" json:\"{{.Name}}\"`" + `
+{{end}}}
+`))
+
+var migrationTemplate = template.Must(template.New("migration").Parse(`package models
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func init() {
+	migrations = append(migrations, Migration{
+		// {{.Name}} lists are filtered and sorted on these fields within an
+		// organization
+		Version: {{.Version}},
+		Name:    "{{.Plural}}_indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("{{.Plural}}").Indexes().CreateMany(ctx, []mongo.IndexModel{
+{{- range .Fields}}
+				{Keys: bson.D{ {Key: "org_id", Value: 1}, {Key: "{{.Name}}", Value: 1} }, Options: options.Index().SetName("org_id_1_{{.Name}}_1")},
+{{- end}}
+			})
+			return err
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			indexes := db.Collection("{{.Plural}}").Indexes()
+			for _, name := range []string{ {{- range $i, $f := .Fields}}{{if $i}}, {{end}}"org_id_1_{{$f.Name}}_1"{{end -}} } {
+				if err := indexes.DropOne(ctx, name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
+`))
+
+var requestTemplate = template.Must(template.New("request").Parse(`package api
+{{if .HasTime}}
+import "time"
+{{end}}
+type {{.Name}}Request struct {
+{{- range .Fields}}
+	{{.GoName}} {{.Type}} ` + "`json:\"{{.Name}}\"{{if .CreateRule}} validate:\"{{.CreateRule}}\"{{end}}`" + `
+{{- end}}
+}
+
+// Update{{.Name}}Request changes the fields that are set and keeps the others
+type Update{{.Name}}Request struct {
+{{- range .Fields}}
+	{{.GoName}} *{{.Type}} ` + "`json:\"{{.Name}},omitempty\"{{if .UpdateRule}} validate:\"{{.UpdateRule}}\"{{end}}`" + `
+{{- end}}
+}
+`))
+
+var handlerTemplate = template.Must(template.New("handler").Parse(`package handlers
+
+import (
+	"{{.Module}}/models"
+	"{{.Module}}/models/api"
+	"{{.Module}}/resource"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// New{{.Name}}Resource serves the {{.Plural}} collection. {{.Name}} documents
+// belong to organizations.
+func New{{.Name}}Resource(db *mongo.Database) *resource.Resource[models.{{.Name}}, api.{{.Name}}Request, api.Update{{.Name}}Request, *models.{{.Name}}] {
+	return resource.New[models.{{.Name}}](db, resource.Config[models.{{.Name}}, api.{{.Name}}Request, api.Update{{.Name}}Request]{
+		Collection: "{{.Plural}}",
+		Scoped:     true,
+		Filters:    []string{ {{- range $i, $f := .Filters}}{{if $i}}, {{end}}"{{$f}}"{{end -}} },
+		Sort:       []string{ {{- range .Fields}}"{{.Name}}", {{end}}"created_at", "updated_at"},
+		New:        new{{.Name}},
+		Apply:      update{{.Name}},
+	})
+}
+
+func new{{.Name}}(request api.{{.Name}}Request) models.{{.Name}} {
+	return models.{{.Name}}{
+{{- range .Fields}}
+		{{.GoName}}: request.{{.GoName}},
+{{- end}}
+	}
+}
+
+// update{{.Name}} copies the fields set in the request
+func update{{.Name}}({{.Var}} *models.{{.Name}}, request api.Update{{.Name}}Request) {
+{{- $var := .Var}}
+{{- range .Fields}}
+	if request.{{.GoName}} != nil {
+		{{$var}}.{{.GoName}} = *request.{{.GoName}}
+	}
+{{- end}}
+}
+`))
+
+var testTemplate = template.Must(template.New("test").Parse(`package handlers
+
+import (
+	"reflect"
+	"testing"
+{{- if .HasTime}}
+	"time"
+{{- end}}
+
+	"{{.Module}}/models/api"
+	"{{.Module}}/utils"
+)
+
+func valid{{.Name}}Request() api.{{.Name}}Request {
+	return api.{{.Name}}Request{
+{{- range .Fields}}
+		{{.GoName}}: {{.Sample}},
+{{- end}}
+	}
+}
+
+func Test{{.Name}}RequestValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(request *api.{{.Name}}Request)
+		wantErr bool
+	}{
+		{"valid", func(request *api.{{.Name}}Request) {}, false},
+{{- range .Fields}}{{if .Invalid}}
+		{"{{.InvalidCase}} {{.Name}}", func(request *api.{{$.Name}}Request) { request.{{.GoName}} = {{.Invalid}} }, true},
+{{- end}}{{end}}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := valid{{.Name}}Request()
+			tt.modify(&request)
+			if err := utils.Validate(request); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpdate{{.Name}}(t *testing.T) {
+	tests := []struct {
+		name    string
+		request api.Update{{.Name}}Request
+		want    api.{{.Name}}Request
+	}{
+		{"no fields", api.Update{{.Name}}Request{}, valid{{.Name}}Request()},
+{{- range .Fields}}
+		{"{{.Name}}", api.Update{{$.Name}}Request{ {{.GoName}}: ptr({{if eq .Type "int64" "float64"}}{{.Type}}({{.Changed}}){{else}}{{.Changed}}{{end}}) }, func() api.{{$.Name}}Request {
+			request := valid{{$.Name}}Request()
+			request.{{.GoName}} = {{.Changed}}
+			return request
+		}()},
+{{- end}}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := utils.Validate(tt.request); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			{{.Var}} := new{{.Name}}(valid{{.Name}}Request())
+			update{{.Name}}(&{{.Var}}, tt.request)
+			if want := new{{.Name}}(tt.want); !reflect.DeepEqual({{.Var}}, want) {
+				t.Errorf("update{{.Name}}() = %+v, want %+v", {{.Var}}, want)
+			}
+		})
+	}
+}
+`))
+
+// ptrTemplate is written with the first generated tests and shared by the
+// next ones
+var ptrTemplate = template.Must(template.New("ptr").Parse(`package handlers
+
+func ptr[T any](v T) *T {
+	return &v
+}
+`))
+
+var routeTemplate = template.Must(template.New("route").Parse(`
+		r.Route("/{{.Route}}", func(r chi.Router) {
+			r.Use(auth.Authenticate)
+			r.Use(middlewares.PasswordChanged)
+			r.Use(tenancy.Resolve)
+
+			// Only the organization's admins until roles are granted
+			// permissions, e.g. "{{.Plural}}.list": {"merchant"}
+			New{{.Name}}Resource(db).Routes(r, middlewares.Permissions{})
+		})
+`))
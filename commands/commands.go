@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kenztech/go-api-starter/config"
+	"github.com/kenztech/go-api-starter/metrics"
+	"github.com/kenztech/go-api-starter/models"
+	"github.com/kenztech/go-api-starter/tracing"
+	"github.com/kenztech/go-api-starter/utils"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const usage = `Usage: go-api-starter <command> [arguments]
+
+Every command accepts --config <file.yaml> and one flag per configuration
+setting (see "go-api-starter serve -h"). Flags override environment
+variables, which override the config file.
+
+Commands:
+  serve                                   start the HTTP server (default)
+  user create --email --password --role [--username --name --status]
+  user set-role --email --role
+  user reset-password --email [--password]
+  user disable --email
+  migrate up
+  migrate down [--steps n]
+  seed [--password]
+  tokens revoke --user <email>
+`
+
+// Execute runs the command named by args[0]. Without arguments it starts the server.
+func Execute(args []string) error {
+	if len(args) == 0 {
+		return serve(nil)
+	}
+
+	switch args[0] {
+	case "serve":
+		return serve(args[1:])
+	case "user":
+		return userCommand(args[1:])
+	case "migrate":
+		return migrateCommand(args[1:])
+	case "seed":
+		return seed(args[1:])
+	case "tokens":
+		return tokensCommand(args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return nil
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// parseFlags parses the command's flags along with the configuration flags
+// and loads the configuration.
+func parseFlags(fs *flag.FlagSet, args []string) (*config.Config, error) {
+	loader := config.NewLoader(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return loader.Load()
+}
+
+// connect configures password hashing and opens the database connection.
+// The returned function closes the connection.
+func connect(cfg *config.Config) (*mongo.Database, func(), error) {
+	utils.SetPasswordHasher(passwordHasher(cfg.Password))
+
+	db, err := models.ConnectDB(cfg.Mongo, metrics.CommandMonitor(), tracing.CommandMonitor())
+	if err != nil {
+		return nil, nil, fmt.Errorf("error connecting to database: %w", err)
+	}
+
+	disconnect := func() {
+		if err := db.Client().Disconnect(context.Background()); err != nil {
+			fmt.Fprintln(os.Stderr, "Error disconnecting from MongoDB:", err)
+		}
+	}
+	return db, disconnect, nil
+}
+
+// newFlagSet returns a flag set that reports errors instead of exiting
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+// validate runs the same struct validation the HTTP handlers use
+func validate(request interface{}) error {
+	if errs := utils.ValidationMessages(request); len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// checkPassword applies the password policy the HTTP handlers use
+func checkPassword(policy *utils.PasswordPolicy, password string, identifiers ...string) error {
+	if errs := policy.Check(password, identifiers...); len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// passwordPolicy builds the password policy, loading the breached password
+// list when one is configured.
+func passwordPolicy(cfg config.PasswordConfig) (*utils.PasswordPolicy, error) {
+	policy := &utils.PasswordPolicy{
+		MinLength:           cfg.MinLength,
+		MaxBytes:            cfg.MaxBytes,
+		RequireUpper:        cfg.RequireUpper,
+		RequireLower:        cfg.RequireLower,
+		RequireDigit:        cfg.RequireDigit,
+		RequireSymbol:       cfg.RequireSymbol,
+		HistorySize:         cfg.HistorySize,
+		DisallowIdentifiers: cfg.DisallowIdentifiers,
+	}
+
+	if cfg.BreachedList != "" {
+		breached, err := utils.LoadBreachedPasswords(cfg.BreachedList)
+		if err != nil {
+			return nil, err
+		}
+		policy.Breached = breached
+	}
+
+	return policy, nil
+}
+
+// passwordHasher builds the configured hasher. The configuration has
+// already been validated, so the algorithm is known.
+func passwordHasher(cfg config.PasswordConfig) utils.PasswordHasher {
+	if cfg.Hasher == "bcrypt" {
+		return &utils.BcryptHasher{Cost: cfg.BcryptCost}
+	}
+	return &utils.Argon2idHasher{
+		Memory:      uint32(cfg.Argon2Memory),
+		Iterations:  uint32(cfg.Argon2Iterations),
+		Parallelism: uint8(cfg.Argon2Parallelism),
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
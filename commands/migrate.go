@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kenztech/go-api-starter/models"
+)
+
+func migrateCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate <up|down> [flags]")
+	}
+
+	fs := newFlagSet("migrate " + args[0])
+	steps := fs.Int("steps", 1, "number of migrations to revert")
+	cfg, err := parseFlags(fs, args[1:])
+	if err != nil {
+		return err
+	}
+
+	db, disconnect, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	switch args[0] {
+	case "up":
+		done, err := models.MigrateUp(ctx, db)
+		for _, m := range done {
+			fmt.Printf("Applied %d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Println("No pending migrations")
+		}
+		return err
+	case "down":
+		if *steps < 1 {
+			return errors.New("--steps must be at least 1")
+		}
+		done, err := models.MigrateDown(ctx, db, *steps)
+		for _, m := range done {
+			fmt.Printf("Reverted %d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Println("No applied migrations")
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kenztech/go-api-starter/models"
+)
+
+// seedUsers are sample accounts for local development
+var seedUsers = []models.User{
+	{Name: "Demo Merchant", Role: "merchant", Email: "merchant@example.com", Username: "merchant", Status: "active"},
+	{Name: "Demo Operator", Role: "operator", Email: "operator@example.com", Username: "operator", Status: "active"},
+}
+
+func seed(args []string) error {
+	fs := newFlagSet("seed")
+	password := fs.String("password", "", "password for the seeded users (generated when empty)")
+	cfg, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	policy, err := passwordPolicy(cfg.Password)
+	if err != nil {
+		return err
+	}
+	if *password == "" {
+		if *password, err = generatePassword(policy); err != nil {
+			return err
+		}
+	}
+
+	db, disconnect, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	users := models.NewMongoUserRepository(db)
+	for _, user := range seedUsers {
+		if err := checkPassword(policy, *password, user.Email, user.Username); err != nil {
+			return err
+		}
+		err := models.CreateUser(ctx, users, &user, *password)
+		if err == models.ErrUserExists {
+			fmt.Printf("Skipped %s: already exists\n", user.Email)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created %s user %s\n", user.Role, user.Email)
+	}
+
+	fmt.Printf("Seeded users use the password: %s\n", *password)
+	return nil
+}
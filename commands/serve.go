@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"context"
+	"log/slog"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/cors"
+	"github.com/kenztech/go-api-starter/handlers"
+	"github.com/kenztech/go-api-starter/health"
+	"github.com/kenztech/go-api-starter/logging"
+	"github.com/kenztech/go-api-starter/metrics"
+	"github.com/kenztech/go-api-starter/middlewares"
+	"github.com/kenztech/go-api-starter/models"
+	"github.com/kenztech/go-api-starter/server"
+	"github.com/kenztech/go-api-starter/tracing"
+	"github.com/kenztech/go-api-starter/utils"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+)
+
+func serve(args []string) error {
+	cfg, err := parseFlags(newFlagSet("serve"), args)
+	if err != nil {
+		return err
+	}
+
+	logger := logging.New(cfg.Log)
+	slog.SetDefault(logger)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		return err
+	}
+
+	policy, err := passwordPolicy(cfg.Password)
+	if err != nil {
+		return err
+	}
+
+	db, disconnect, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	slog.Info("Database connection established", "database", db.Name())
+
+	if cfg.Mongo.Migrate {
+		if err := migrate(db); err != nil {
+			disconnect()
+			return err
+		}
+	}
+
+	// Make sure the first admin can be created
+	models.BootstrapAdmin(db, cfg.Bootstrap)
+
+	tokens := utils.NewTokenManager(cfg.JWT.Secret, cfg.JWT.SessionTTL, cfg.JWT.ResetTTL)
+	mailer := &utils.EmailSender{
+		SMTPPort:   cfg.Mail.Port,
+		SMTPServer: cfg.Mail.Host,
+		Username:   cfg.Mail.Username,
+		Password:   cfg.Mail.Password,
+		From:       cfg.Mail.From,
+	}
+
+	r := chi.NewRouter()
+	r.Use(cors.Handler(cors.Options{
+		AllowedOrigins:   cfg.CORS.AllowedOrigins,
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-Request-ID", "If-Match", "If-None-Match", "Idempotency-Key", "X-Organization", "traceparent", "tracestate"},
+		ExposedHeaders:   []string{"Link", "X-Total-Count", "Set-Cookie", "X-Impersonated-By", "X-Request-ID", "ETag", "Idempotent-Replayed"},
+		AllowCredentials: cfg.CORS.AllowCredentials,
+	}))
+	r.Use(middleware.StripSlashes)
+	r.Use(middlewares.RequestID(cfg.Server.TrustRequestID))
+	r.Use(tracing.Middleware)
+	r.Use(logging.Middleware(logger))
+	if cfg.Metrics.Enabled {
+		r.Use(metrics.Middleware)
+	}
+	r.Use(middleware.Recoverer)
+
+	srv := server.New(cfg.Server, r)
+	srv.AddWorker(server.WorkerFunc(utils.SweepOTPs))
+
+	if cfg.Metrics.Enabled {
+		if cfg.Metrics.Addr != "" {
+			admin := chi.NewRouter()
+			admin.Handle(cfg.Metrics.Path, metrics.Handler())
+			srv.AddWorker(server.NewAdminServer(cfg.Metrics.Addr, admin))
+		} else {
+			r.Handle(cfg.Metrics.Path, metrics.Handler())
+		}
+	}
+
+	checks := health.NewRegistry(cfg.Health.CheckTimeout)
+	checks.Register(health.NewCheck("server", srv.CheckReady))
+	checks.Register(health.NewCheck("mongo", func(ctx context.Context) error {
+		return db.Client().Ping(ctx, readpref.Primary())
+	}))
+	checks.Register(health.NewCheck("workers", srv.CheckWorkers))
+	if cfg.Mail.Host != "" {
+		checks.Register(health.NewCheck("mail", mailer.Ping))
+	}
+	r.Get("/healthz", checks.Liveness)
+	r.Get("/readyz", checks.Readiness)
+
+	handlers.InitRoutes(r, db, cfg, policy, tokens, mailer)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		// A second signal kills the process instead of waiting for the drain.
+		<-ctx.Done()
+		stop()
+	}()
+
+	err = srv.Run(ctx)
+
+	// Flush the spans of the last requests before exiting.
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Error("Error flushing traces", "error", err)
+	}
+	cancel()
+
+	// The database goes last, once no request or worker can use it anymore.
+	disconnect()
+	slog.Info("Database connection closed")
+
+	return err
+}
+
+// migrate applies pending migrations before the server starts. Instances
+// starting together take turns through the migration lock.
+func migrate(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	done, err := models.MigrateUp(ctx, db)
+	for _, m := range done {
+		slog.Info("Migration applied", "version", m.Version, "name", m.Name)
+	}
+	return err
+}
@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kenztech/go-api-starter/models"
+)
+
+func tokensCommand(args []string) error {
+	if len(args) == 0 || args[0] != "revoke" {
+		return errors.New("usage: tokens revoke --user <email>")
+	}
+
+	fs := newFlagSet("tokens revoke")
+	email := fs.String("user", "", "email of the user whose tokens are revoked")
+	cfg, err := parseFlags(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if *email == "" {
+		return errors.New("--user is required")
+	}
+
+	db, disconnect, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	users := models.NewMongoUserRepository(db)
+	user, err := users.FindByEmail(ctx, *email)
+	if err != nil {
+		return userError(*email, err)
+	}
+	if err := models.RevokeTokens(ctx, users, user); err != nil {
+		return err
+	}
+
+	fmt.Printf("Revoked all tokens of %s\n", *email)
+	return nil
+}
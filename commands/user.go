@@ -0,0 +1,255 @@
+package commands
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/kenztech/go-api-starter/models"
+	"github.com/kenztech/go-api-starter/models/api"
+	"github.com/kenztech/go-api-starter/utils"
+)
+
+type roleRequest struct {
+	Role string `validate:"required,oneof=admin merchant operator"`
+}
+
+func userCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: user <create|set-role|reset-password|disable> [flags]")
+	}
+
+	switch args[0] {
+	case "create":
+		return createUser(args[1:])
+	case "set-role":
+		return setRole(args[1:])
+	case "reset-password":
+		return resetPassword(args[1:])
+	case "disable":
+		return disableUser(args[1:])
+	default:
+		return fmt.Errorf("unknown user command %q", args[0])
+	}
+}
+
+func createUser(args []string) error {
+	var request api.UserRequest
+	fs := newFlagSet("user create")
+	fs.StringVar(&request.Email, "email", "", "email address")
+	fs.StringVar(&request.Username, "username", "", "username")
+	fs.StringVar(&request.Name, "name", "", "display name")
+	fs.StringVar(&request.Password, "password", "", "password")
+	fs.StringVar(&request.Role, "role", "", "role: admin, merchant or operator")
+	fs.StringVar(&request.Status, "status", "active", "status: active, inactive or banned")
+	cfg, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if err := validate(request); err != nil {
+		return err
+	}
+
+	policy, err := passwordPolicy(cfg.Password)
+	if err != nil {
+		return err
+	}
+	if err := checkPassword(policy, request.Password, request.Email, request.Username); err != nil {
+		return err
+	}
+
+	db, disconnect, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user := models.User{
+		Name:     request.Name,
+		Role:     request.Role,
+		Email:    request.Email,
+		Status:   request.Status,
+		Username: request.Username,
+	}
+	if err := models.CreateUser(ctx, models.NewMongoUserRepository(db), &user, request.Password); err != nil {
+		return err
+	}
+
+	fmt.Printf("Created %s user %s (%s)\n", user.Role, user.Email, user.ID.Hex())
+	return nil
+}
+
+func setRole(args []string) error {
+	var email string
+	var request roleRequest
+	fs := newFlagSet("user set-role")
+	fs.StringVar(&email, "email", "", "email of the user")
+	fs.StringVar(&request.Role, "role", "", "role: admin, merchant or operator")
+	cfg, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if email == "" {
+		return errors.New("--email is required")
+	}
+	if err := validate(request); err != nil {
+		return err
+	}
+
+	db, disconnect, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	users := models.NewMongoUserRepository(db)
+	user, err := users.FindByEmail(ctx, email)
+	if err != nil {
+		return userError(email, err)
+	}
+
+	// Tokens carry the role, so existing sessions must not keep the old one.
+	user.Role = request.Role
+	if err := models.RevokeTokens(ctx, users, user); err != nil {
+		return err
+	}
+
+	fmt.Printf("Set role of %s to %s\n", email, request.Role)
+	return nil
+}
+
+func resetPassword(args []string) error {
+	var email, password string
+	fs := newFlagSet("user reset-password")
+	fs.StringVar(&email, "email", "", "email of the user")
+	fs.StringVar(&password, "password", "", "new password (generated when empty)")
+	cfg, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if email == "" {
+		return errors.New("--email is required")
+	}
+
+	policy, err := passwordPolicy(cfg.Password)
+	if err != nil {
+		return err
+	}
+
+	db, disconnect, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	users := models.NewMongoUserRepository(db)
+	user, err := users.FindByEmail(ctx, email)
+	if err != nil {
+		return userError(email, err)
+	}
+
+	generated := password == ""
+	if generated {
+		if password, err = generatePassword(policy); err != nil {
+			return err
+		}
+	}
+	if err := checkPassword(policy, password, user.Email, user.Username); err != nil {
+		return err
+	}
+
+	// SetPassword also revokes the user's sessions.
+	history := policy.NextHistory(user.Password, user.PasswordHistory)
+	if err := models.SetPassword(ctx, users, user, password, history, true); err != nil {
+		return err
+	}
+
+	if generated {
+		fmt.Printf("Password of %s reset to: %s\n", email, password)
+	} else {
+		fmt.Printf("Password of %s reset\n", email)
+	}
+	fmt.Println("The user must change it on next login.")
+	return nil
+}
+
+func disableUser(args []string) error {
+	var email string
+	fs := newFlagSet("user disable")
+	fs.StringVar(&email, "email", "", "email of the user")
+	cfg, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if email == "" {
+		return errors.New("--email is required")
+	}
+
+	db, disconnect, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	users := models.NewMongoUserRepository(db)
+	user, err := users.FindByEmail(ctx, email)
+	if err != nil {
+		return userError(email, err)
+	}
+
+	user.Status = "inactive"
+	if err := models.RevokeTokens(ctx, users, user); err != nil {
+		return err
+	}
+
+	fmt.Printf("Disabled %s and revoked its tokens\n", email)
+	return nil
+}
+
+func userError(email string, err error) error {
+	if errors.Is(err, models.ErrUserNotFound) {
+		return fmt.Errorf("user %s not found", email)
+	}
+	return err
+}
+
+// generatePassword returns a random password accepted by the policy
+func generatePassword(policy *utils.PasswordPolicy) (string, error) {
+	const chars = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789!@#$%&*?"
+
+	length := policy.MinLength
+	if length < 16 {
+		length = 16
+	}
+
+	for attempt := 0; attempt < 100; attempt++ {
+		password := make([]byte, length)
+		for i := range password {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+			if err != nil {
+				return "", err
+			}
+			password[i] = chars[n.Int64()]
+		}
+		if len(policy.Check(string(password))) == 0 {
+			return string(password), nil
+		}
+	}
+	return "", errors.New("could not generate a password satisfying the password policy")
+}
@@ -0,0 +1,296 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the whole application configuration. Every field can be set in
+// the config file (yaml tag), the environment (env tag) or a command line
+// flag named after its yaml path, e.g. --mongo.uri.
+type Config struct {
+	Server      ServerConfig      `yaml:"server"`
+	Mongo       MongoConfig       `yaml:"mongo"`
+	JWT         JWTConfig         `yaml:"jwt"`
+	Mail        MailConfig        `yaml:"mail"`
+	CORS        CORSConfig        `yaml:"cors"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	Password    PasswordConfig    `yaml:"password"`
+	Bootstrap   BootstrapConfig   `yaml:"bootstrap"`
+	Health      HealthConfig      `yaml:"health"`
+	Log         LogConfig         `yaml:"log"`
+	Metrics     MetricsConfig     `yaml:"metrics"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Tenancy     TenancyConfig     `yaml:"tenancy"`
+}
+
+type ServerConfig struct {
+	Port              string        `yaml:"port" env:"PORT" usage:"HTTP port"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT" usage:"maximum duration for reading a request"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT" usage:"maximum duration for reading request headers"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT" usage:"maximum duration for writing a response"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT" usage:"how long keep-alive connections stay idle"`
+	DrainDelay        time.Duration `yaml:"drain_delay" env:"SERVER_DRAIN_DELAY" usage:"time between reporting not ready and closing the listener"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT" usage:"maximum time to let in-flight requests finish"`
+	TrustRequestID    bool          `yaml:"trust_request_id" env:"SERVER_TRUST_REQUEST_ID" usage:"reuse the incoming X-Request-ID header (only behind a trusted proxy)"`
+}
+
+type MongoConfig struct {
+	URI      string `yaml:"uri" env:"MONGO_URI" usage:"MongoDB connection string"`
+	Database string `yaml:"database" env:"MONGO_DB" usage:"MongoDB database name"`
+	Migrate  bool   `yaml:"migrate" env:"MONGO_MIGRATE" usage:"apply pending migrations at startup"`
+}
+
+type JWTConfig struct {
+	Secret           string        `yaml:"secret" env:"JWT_SECRET" usage:"secret used to sign tokens"`
+	SessionTTL       time.Duration `yaml:"session_ttl" env:"JWT_SESSION_TTL" usage:"lifetime of session tokens"`
+	ResetTTL         time.Duration `yaml:"reset_ttl" env:"JWT_RESET_TTL" usage:"lifetime of password reset tokens"`
+	ImpersonationTTL time.Duration `yaml:"impersonation_ttl" env:"IMPERSONATION_TTL" usage:"lifetime of impersonation tokens"`
+}
+
+type MailConfig struct {
+	Host             string `yaml:"host" env:"SMTP_HOST" usage:"SMTP server, emails are only logged when empty"`
+	Port             int    `yaml:"port" env:"SMTP_PORT" usage:"SMTP port"`
+	Username         string `yaml:"username" env:"SMTP_USERNAME" usage:"SMTP username"`
+	Password         string `yaml:"password" env:"SMTP_PASSWORD" usage:"SMTP password"`
+	From             string `yaml:"from" env:"MAIL_FROM" usage:"sender address"`
+	ResetPasswordURL string `yaml:"reset_password_url" env:"RESET_PASSWORD_URL" usage:"frontend page handling password reset links"`
+}
+
+type CORSConfig struct {
+	AllowedOrigins   []string `yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS" usage:"comma separated list of allowed origins"`
+	AllowCredentials bool     `yaml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS" usage:"allow cookies on cross origin requests"`
+}
+
+type RateLimitConfig struct {
+	Enabled  bool          `yaml:"enabled" env:"RATE_LIMIT_ENABLED" usage:"rate limit authentication endpoints"`
+	Requests int           `yaml:"requests" env:"RATE_LIMIT_REQUESTS" usage:"requests allowed per client and window"`
+	Window   time.Duration `yaml:"window" env:"RATE_LIMIT_WINDOW" usage:"rate limit window"`
+}
+
+type PasswordConfig struct {
+	MinLength           int    `yaml:"min_length" env:"PASSWORD_MIN_LENGTH" usage:"minimum password length"`
+	MaxBytes            int    `yaml:"max_bytes" env:"PASSWORD_MAX_BYTES" usage:"maximum password length in bytes (at most 72)"`
+	RequireUpper        bool   `yaml:"require_upper" env:"PASSWORD_REQUIRE_UPPER" usage:"require an uppercase letter"`
+	RequireLower        bool   `yaml:"require_lower" env:"PASSWORD_REQUIRE_LOWER" usage:"require a lowercase letter"`
+	RequireDigit        bool   `yaml:"require_digit" env:"PASSWORD_REQUIRE_DIGIT" usage:"require a digit"`
+	RequireSymbol       bool   `yaml:"require_symbol" env:"PASSWORD_REQUIRE_SYMBOL" usage:"require a symbol"`
+	HistorySize         int    `yaml:"history_size" env:"PASSWORD_HISTORY_SIZE" usage:"number of previous passwords that cannot be reused"`
+	DisallowIdentifiers bool   `yaml:"disallow_identifiers" env:"PASSWORD_DISALLOW_IDENTIFIERS" usage:"reject passwords containing the email or username"`
+	BreachedList        string `yaml:"breached_list" env:"PASSWORD_BREACHED_LIST" usage:"file of SHA-1 hashes of breached passwords"`
+	Hasher              string `yaml:"hasher" env:"PASSWORD_HASHER" usage:"password hashing algorithm: argon2id or bcrypt"`
+	BcryptCost          int    `yaml:"bcrypt_cost" env:"BCRYPT_COST" usage:"bcrypt cost"`
+	Argon2Memory        int    `yaml:"argon2_memory" env:"ARGON2_MEMORY" usage:"argon2id memory in KiB"`
+	Argon2Iterations    int    `yaml:"argon2_iterations" env:"ARGON2_ITERATIONS" usage:"argon2id iterations"`
+	Argon2Parallelism   int    `yaml:"argon2_parallelism" env:"ARGON2_PARALLELISM" usage:"argon2id parallelism"`
+}
+
+type BootstrapConfig struct {
+	Mode          string        `yaml:"mode" env:"ADMIN_BOOTSTRAP" usage:"admin bootstrap mode: auto or disabled"`
+	AdminEmail    string        `yaml:"admin_email" env:"BOOTSTRAP_ADMIN_EMAIL" usage:"email of the bootstrap admin"`
+	AdminPassword string        `yaml:"admin_password" env:"BOOTSTRAP_ADMIN_PASSWORD" usage:"initial password of the bootstrap admin"`
+	AdminUsername string        `yaml:"admin_username" env:"BOOTSTRAP_ADMIN_USERNAME" usage:"username of the bootstrap admin"`
+	SetupTokenTTL time.Duration `yaml:"setup_token_ttl" env:"SETUP_TOKEN_TTL" usage:"lifetime of the one-time setup token"`
+}
+
+type HealthConfig struct {
+	CheckTimeout time.Duration `yaml:"check_timeout" env:"HEALTH_CHECK_TIMEOUT" usage:"maximum time each readiness check may take"`
+}
+
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled" env:"METRICS_ENABLED" usage:"expose Prometheus metrics"`
+	Path    string `yaml:"path" env:"METRICS_PATH" usage:"path of the metrics endpoint"`
+	Addr    string `yaml:"addr" env:"METRICS_ADDR" usage:"serve metrics on this address (e.g. :9090) instead of the API port"`
+}
+
+// TracingConfig enables OpenTelemetry tracing. The OTLP exporter itself is
+// configured by the standard OTEL_EXPORTER_OTLP_* environment variables.
+type TracingConfig struct {
+	Enabled     bool   `yaml:"enabled" env:"TRACING_ENABLED" usage:"export OpenTelemetry traces over OTLP/HTTP"`
+	ServiceName string `yaml:"service_name" env:"OTEL_SERVICE_NAME" usage:"service name reported in traces"`
+}
+
+type IdempotencyConfig struct {
+	TTL time.Duration `yaml:"ttl" env:"IDEMPOTENCY_TTL" usage:"how long responses to requests with an Idempotency-Key are replayed"`
+}
+
+type TenancyConfig struct {
+	Domain string `yaml:"domain" env:"TENANCY_DOMAIN" usage:"domain whose subdomains name organizations, e.g. app.example.com for acme.app.example.com"`
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level" env:"LOG_LEVEL" usage:"log level: debug, info, warn or error"`
+	Format string `yaml:"format" env:"LOG_FORMAT" usage:"log format: json or text"`
+}
+
+// Default returns the configuration used when nothing overrides it
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:              "8080",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			DrainDelay:        5 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Mongo: MongoConfig{
+			URI:      "mongodb://localhost:27017",
+			Database: "test",
+			Migrate:  true,
+		},
+		JWT: JWTConfig{
+			SessionTTL:       24 * time.Hour,
+			ResetTTL:         15 * time.Minute,
+			ImpersonationTTL: 30 * time.Minute,
+		},
+		Mail: MailConfig{
+			Port:             587,
+			ResetPasswordURL: "http://localhost:5173/reset-password",
+		},
+		CORS: CORSConfig{
+			AllowedOrigins:   []string{"http://localhost:5173"},
+			AllowCredentials: true,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:  true,
+			Requests: 20,
+			Window:   time.Minute,
+		},
+		Password: PasswordConfig{
+			MinLength:           8,
+			MaxBytes:            72,
+			RequireUpper:        true,
+			RequireLower:        true,
+			RequireDigit:        true,
+			HistorySize:         5,
+			DisallowIdentifiers: true,
+			Hasher:              "argon2id",
+			BcryptCost:          10,
+			Argon2Memory:        19 * 1024,
+			Argon2Iterations:    2,
+			Argon2Parallelism:   1,
+		},
+		Bootstrap: BootstrapConfig{
+			Mode:          "auto",
+			AdminUsername: "admin",
+			SetupTokenTTL: time.Hour,
+		},
+		Health: HealthConfig{
+			CheckTimeout: 2 * time.Second,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Metrics: MetricsConfig{
+			Enabled: true,
+			Path:    "/metrics",
+		},
+		Tracing: TracingConfig{
+			ServiceName: "go-api-starter",
+		},
+		Idempotency: IdempotencyConfig{
+			TTL: 24 * time.Hour,
+		},
+	}
+}
+
+// Validate checks the configuration and reports every problem at once
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	port, err := strconv.Atoi(c.Server.Port)
+	check(err == nil && port > 0 && port < 65536, "server.port: %q is not a valid port", c.Server.Port)
+	check(c.Server.ReadTimeout > 0, "server.read_timeout: must be positive")
+	check(c.Server.ReadHeaderTimeout > 0, "server.read_header_timeout: must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout: must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout: must be positive")
+	check(c.Server.DrainDelay >= 0, "server.drain_delay: must not be negative")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout: must be positive")
+
+	u, err := url.Parse(c.Mongo.URI)
+	check(err == nil && (u.Scheme == "mongodb" || u.Scheme == "mongodb+srv"), "mongo.uri: must be a mongodb:// or mongodb+srv:// URI")
+	check(c.Mongo.Database != "", "mongo.database: is required")
+
+	check(len(c.JWT.Secret) >= 32, "jwt.secret: must be at least 32 characters")
+	check(c.JWT.SessionTTL > 0, "jwt.session_ttl: must be positive")
+	check(c.JWT.ResetTTL > 0, "jwt.reset_ttl: must be positive")
+	check(c.JWT.ImpersonationTTL > 0, "jwt.impersonation_ttl: must be positive")
+
+	if c.Mail.Host != "" {
+		check(c.Mail.Port > 0 && c.Mail.Port < 65536, "mail.port: %d is not a valid port", c.Mail.Port)
+		check(c.Mail.From != "", "mail.from: is required when mail.host is set")
+	}
+	_, err = url.ParseRequestURI(c.Mail.ResetPasswordURL)
+	check(err == nil, "mail.reset_password_url: %q is not a valid URL", c.Mail.ResetPasswordURL)
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		check(origin == "*" || isOrigin(origin), "cors.allowed_origins: %q is not a valid origin", origin)
+	}
+
+	if c.RateLimit.Enabled {
+		check(c.RateLimit.Requests > 0, "rate_limit.requests: must be positive")
+		check(c.RateLimit.Window > 0, "rate_limit.window: must be positive")
+	}
+
+	check(c.Password.MinLength > 0, "password.min_length: must be positive")
+	check(c.Password.MaxBytes > 0 && c.Password.MaxBytes <= 72, "password.max_bytes: must be between 1 and 72")
+	check(c.Password.MinLength <= c.Password.MaxBytes, "password.min_length: must not exceed password.max_bytes")
+	check(c.Password.HistorySize >= 0, "password.history_size: must not be negative")
+	switch c.Password.Hasher {
+	case "argon2id":
+		check(c.Password.Argon2Memory >= 8*c.Password.Argon2Parallelism, "password.argon2_memory: must be at least 8 KiB per thread")
+		check(c.Password.Argon2Iterations > 0, "password.argon2_iterations: must be positive")
+		check(c.Password.Argon2Parallelism > 0 && c.Password.Argon2Parallelism < 256, "password.argon2_parallelism: must be between 1 and 255")
+	case "bcrypt":
+		check(c.Password.BcryptCost >= 4 && c.Password.BcryptCost <= 31, "password.bcrypt_cost: must be between 4 and 31")
+	default:
+		check(false, "password.hasher: %q is not one of argon2id, bcrypt", c.Password.Hasher)
+	}
+
+	check(c.Bootstrap.Mode == "auto" || c.Bootstrap.Mode == "disabled", "bootstrap.mode: %q is not one of auto, disabled", c.Bootstrap.Mode)
+	check((c.Bootstrap.AdminEmail == "") == (c.Bootstrap.AdminPassword == ""), "bootstrap: admin_email and admin_password must be set together")
+	check(c.Bootstrap.SetupTokenTTL > 0, "bootstrap.setup_token_ttl: must be positive")
+
+	check(c.Health.CheckTimeout > 0, "health.check_timeout: must be positive")
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Log.Level)) == nil, "log.level: %q is not one of debug, info, warn, error", c.Log.Level)
+	check(c.Log.Format == "json" || c.Log.Format == "text", "log.format: %q is not one of json, text", c.Log.Format)
+
+	if c.Metrics.Enabled {
+		check(strings.HasPrefix(c.Metrics.Path, "/"), "metrics.path: %q must start with /", c.Metrics.Path)
+		if c.Metrics.Addr != "" {
+			_, port, err := net.SplitHostPort(c.Metrics.Addr)
+			check(err == nil && port != c.Server.Port, "metrics.addr: %q must be host:port on a port other than server.port", c.Metrics.Addr)
+		}
+	}
+
+	if c.Tracing.Enabled {
+		check(c.Tracing.ServiceName != "", "tracing.service_name: is required when tracing is enabled")
+	}
+
+	check(c.Idempotency.TTL > 0, "idempotency.ttl: must be positive")
+	check(!strings.HasPrefix(c.Tenancy.Domain, ".") && !strings.Contains(c.Tenancy.Domain, ":"), "tenancy.domain: must be a host name without port, e.g. app.example.com")
+
+	return errors.Join(errs...)
+}
+
+func isOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.Path == ""
+}
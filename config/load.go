@@ -0,0 +1,157 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Loader builds the configuration from, in increasing precedence: defaults,
+// a YAML file, environment variables (including a .env file) and flags.
+type Loader struct {
+	fs   *flag.FlagSet
+	file *string
+}
+
+// NewLoader registers --config and one flag per configuration field on fs.
+// Call Load once fs has been parsed.
+func NewLoader(fs *flag.FlagSet) *Loader {
+	l := &Loader{fs: fs}
+	l.file = fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML config file")
+
+	walk(reflect.ValueOf(Default()).Elem(), "", func(field reflect.Value, sf reflect.StructField, path string) {
+		usage := fmt.Sprintf("%s (env %s)", sf.Tag.Get("usage"), sf.Tag.Get("env"))
+		if value := format(field); value != "" {
+			usage = fmt.Sprintf("%s (env %s, default %s)", sf.Tag.Get("usage"), sf.Tag.Get("env"), value)
+		}
+		fs.String(path, "", usage)
+	})
+	return l
+}
+
+// Load builds and validates the configuration, reporting every error at once
+func (l *Loader) Load() (*Config, error) {
+	cfg := Default()
+
+	if *l.file != "" {
+		data, err := os.ReadFile(*l.file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", *l.file, err)
+		}
+	}
+
+	// A missing .env file is fine, real environment variables take precedence.
+	_ = godotenv.Load()
+
+	var errs []error
+	root := reflect.ValueOf(cfg).Elem()
+
+	walk(root, "", func(field reflect.Value, sf reflect.StructField, path string) {
+		name := sf.Tag.Get("env")
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+		if err := set(field, value); err != nil {
+			errs = append(errs, fmt.Errorf("env %s: %w", name, err))
+		}
+	})
+
+	fields := make(map[string]reflect.Value)
+	walk(root, "", func(field reflect.Value, sf reflect.StructField, path string) {
+		fields[path] = field
+	})
+	l.fs.Visit(func(f *flag.Flag) {
+		field, ok := fields[f.Name]
+		if !ok {
+			return
+		}
+		if err := set(field, f.Value.String()); err != nil {
+			errs = append(errs, fmt.Errorf("flag --%s: %w", f.Name, err))
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return cfg, nil
+}
+
+// walk calls fn for every leaf field with its dotted yaml path
+func walk(v reflect.Value, prefix string, fn func(field reflect.Value, sf reflect.StructField, path string)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		path := strings.TrimPrefix(prefix+"."+sf.Tag.Get("yaml"), ".")
+		if sf.Type.Kind() == reflect.Struct {
+			walk(v.Field(i), path, fn)
+			continue
+		}
+		fn(v.Field(i), sf, path)
+	}
+}
+
+func set(field reflect.Value, value string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		field.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported config type %s", field.Type())
+	}
+	return nil
+}
+
+func format(field reflect.Value) string {
+	if field.Type() == durationType {
+		return time.Duration(field.Int()).String()
+	}
+	if field.Kind() == reflect.Slice {
+		return strings.Join(field.Interface().([]string), ",")
+	}
+	return fmt.Sprint(field.Interface())
+}
@@ -2,136 +2,224 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/kenztech/go-api-starter/apperror"
+	"github.com/kenztech/go-api-starter/config"
+	"github.com/kenztech/go-api-starter/logging"
+	"github.com/kenztech/go-api-starter/metrics"
+	"github.com/kenztech/go-api-starter/middlewares"
 	"github.com/kenztech/go-api-starter/models"
 	"github.com/kenztech/go-api-starter/models/api"
 	"github.com/kenztech/go-api-starter/utils"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
 type AuthHandler struct {
-	db *mongo.Database
+	users  models.UserRepository
+	orgs   models.OrganizationRepository
+	audit  models.AuditRecorder
+	cfg    *config.Config
+	policy *utils.PasswordPolicy
+	tokens *utils.TokenManager
+	mailer *utils.EmailSender
 }
 
-func NewAuthHandler(db *mongo.Database) *AuthHandler {
-	return &AuthHandler{db}
+func NewAuthHandler(users models.UserRepository, orgs models.OrganizationRepository, audit models.AuditRecorder, cfg *config.Config, policy *utils.PasswordPolicy, tokens *utils.TokenManager, mailer *utils.EmailSender) *AuthHandler {
+	return &AuthHandler{users, orgs, audit, cfg, policy, tokens, mailer}
 }
 
-func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+func (h *AuthHandler) Me(r *http.Request) (api.UserResponse, error) {
 	// Retrieve user data from context (assumes user data is stored in the context)
 	data, ok := utils.GetUserDataFromContext(r.Context())
 	if !ok {
-		utils.SendError(w, "Unable to retrieve user from context", http.StatusInternalServerError)
-		return
+		return api.UserResponse{}, apperror.Internal(errNoUserData)
 	}
 
-	// Log email to debug
-	log.Printf("User email retrieved from context: %s", data.Email)
+	id, err := utils.ParseID(data.ID)
+	if err != nil {
+		return api.UserResponse{}, err
+	}
+
+	user, err := h.users.FindByID(r.Context(), id)
+	if err != nil {
+		return api.UserResponse{}, userError(err)
+	}
 
-	// Get user from MongoDB by email
-	var user models.User
-	collection := h.db.Collection("users")
-	filter := bson.M{"email": data.Email}
+	return api.UserResponse{Success: true, User: toUserData(user)}, nil
+}
+
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) error {
+	var request api.LoginRequest
+	if err := utils.DecodeJSON(r, &request); err != nil {
+		metrics.LoginFailed(metrics.ReasonInvalidInput)
+		return err
+	}
 
-	err := collection.FindOne(r.Context(), filter).Decode(&user)
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var user *models.User
+	var err error
+	if request.Email != "" {
+		user, err = h.users.FindByEmail(ctx, request.Email)
+	} else {
+		user, err = h.users.FindByUsername(ctx, request.Username)
+	}
+	if errors.Is(err, models.ErrUserNotFound) {
+		metrics.LoginFailed(metrics.ReasonUnknownUser)
+		return apperror.ErrInvalidCredentials
+	}
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			utils.SendError(w, "User not found", http.StatusNotFound)
+		metrics.LoginFailed(metrics.ReasonInternalError)
+		return err
+	}
+
+	if !utils.ComparePassword(user.Password, request.Password) {
+		metrics.LoginFailed(metrics.ReasonBadPassword)
+		return apperror.ErrInvalidCredentials
+	}
+
+	if user.Status != "active" {
+		metrics.LoginFailed(metrics.ReasonInactive)
+		return apperror.ErrAccountInactive
+	}
+
+	// Upgrade hashes made with an outdated algorithm or parameters while the
+	// plain text password is at hand. Failing to do so must not block login.
+	if utils.PasswordNeedsRehash(user.Password) {
+		if hashedPassword, err := utils.HashPassword(request.Password); err != nil {
+			logging.FromContext(ctx).Error("Error rehashing password", "error", err)
 		} else {
-			utils.SendError(w, "Error retrieving user", http.StatusInternalServerError)
+			rehashed := *user
+			rehashed.Password = hashedPassword
+			if err := h.users.Update(ctx, &rehashed); err != nil {
+				logging.FromContext(ctx).Error("Error storing rehashed password", "error", err)
+			}
+		}
+	}
+
+	// The organization picked at login is the session's default tenant.
+	var orgID string
+	if request.Organization != "" {
+		org, _, err := models.FindMemberOrganization(ctx, h.orgs, request.Organization, user.ID, user.Role)
+		if err != nil {
+			if errors.Is(err, models.ErrNotMember) || errors.Is(err, models.ErrOrganizationNotFound) {
+				metrics.LoginFailed(metrics.ReasonInvalidInput)
+			} else {
+				metrics.LoginFailed(metrics.ReasonInternalError)
+			}
+			return organizationError(err)
 		}
-		return
+		orgID = org.ID.Hex()
 	}
 
-	// Prepare the response object
-	response := api.UserResponse{
+	token, err := h.tokens.GenerateRefreshToken(user.ID.Hex(), user.Email, user.Role, orgID, user.MustChangePassword)
+	if err != nil {
+		metrics.LoginFailed(metrics.ReasonInternalError)
+		return err
+	}
+	metrics.LoginSucceeded()
+
+	setTokenCookie(w, token, time.Now().Add(h.tokens.SessionTTL()))
+
+	utils.SendJSON(w, http.StatusOK, api.UserResponse{Success: true, User: toUserData(user)})
+	return nil
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	clearTokenCookie(w)
+
+	res := api.SuccessResponse{
 		Success: true,
-		User: api.UserData{
-			ID:     uint(user.ID[0]),
-			Role:   user.Role,
-			Email:  user.Email,
-			Status: user.Status,
-		},
+		Message: "Logged out successfully.",
 	}
 
-	// Send the JSON response with user details
-	utils.SendJSON(w, http.StatusOK, response)
+	utils.SendJSON(w, http.StatusOK, res)
 }
 
-func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
-	var request api.LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		utils.SendError(w, "invalid request body", http.StatusBadRequest)
-		return
+// StopImpersonation ends an impersonation session and restores the admin's own session
+func (h *AuthHandler) StopImpersonation(w http.ResponseWriter, r *http.Request) error {
+	data, ok := utils.GetUserDataFromContext(r.Context())
+	if !ok {
+		return apperror.Internal(errNoUserData)
+	}
+	if !data.IsImpersonated() {
+		return errNotImpersonating
 	}
 
-	if !utils.ValidateStruct(w, request) {
-		return
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	actor, err := h.users.FindByEmail(ctx, data.ImpersonatorEmail)
+	if err != nil || actor.Role != "admin" {
+		// The actor is gone or no longer an admin: drop the session entirely.
+		clearTokenCookie(w)
+		return apperror.ErrUnauthorized
 	}
 
-	// Get user from MongoDB
-	var user models.User
-	filter := bson.M{}
+	token, err := h.tokens.GenerateRefreshToken(actor.ID.Hex(), actor.Email, actor.Role, "", actor.MustChangePassword)
+	if err != nil {
+		return err
+	}
 
-	if request.Email != "" {
-		filter["email"] = request.Email
-	} else {
-		filter["username"] = request.Username
+	err = h.audit.Record(ctx, models.AuditLog{
+		Action:      models.AuditImpersonationStop,
+		ActorEmail:  actor.Email,
+		TargetEmail: data.Email,
+		IPAddress:   r.RemoteAddr,
+		UserAgent:   r.UserAgent(),
+	})
+	if err != nil {
+		return fmt.Errorf("recording impersonation audit: %w", err)
 	}
 
-	log.Printf("User to find: Email: %s, Username: %s, Password: %s", request.Email, request.Username, request.Password)
-	log.Printf("Constructed filter: %+v", filter)
+	setTokenCookie(w, token, time.Now().Add(h.tokens.SessionTTL()))
+	w.Header().Del(middlewares.ImpersonatedByHeader)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	utils.SendJSON(w, http.StatusOK, api.UserResponse{Success: true, User: toUserData(actor)})
+	return nil
+}
 
-	err := h.db.Collection("users").FindOne(ctx, filter).Decode(&user)
-	if err != nil {
-		log.Printf("Error finding user: %+v", err)
-		utils.SendError(w, "User not found", http.StatusUnauthorized)
-		return
+func (h *AuthHandler) Register(r *http.Request) (api.UserResponse, error) {
+	var request api.RegisterRequest
+	if err := utils.DecodeJSON(r, &request); err != nil {
+		return api.UserResponse{}, err
 	}
 
-	if !utils.ComparePassword(user.Password, request.Password) {
-		utils.SendError(w, "Invalid credentials", http.StatusUnauthorized)
-		return
+	if err := h.policy.Validate(request.Password, request.Email, request.Username); err != nil {
+		return api.UserResponse{}, err
 	}
 
-	token, err := utils.GenerateRefreshToken(user.Email, user.Role)
-	if err != nil {
-		utils.SendError(w, "Failed to generate refresh token", http.StatusInternalServerError)
-		return
+	user := models.User{
+		Name:     request.Name,
+		Role:     "merchant",
+		Email:    request.Email,
+		Status:   "active",
+		Username: request.Username,
 	}
 
-	response := api.UserResponse{
-		Success: true,
-		User: api.UserData{
-			ID:       uint(user.ID[0]),
-			Role:     user.Role,
-			Email:    user.Email,
-			Status:   user.Status,
-			Username: user.Username,
-		},
+	if err := insertUser(r.Context(), h.users, &user, request.Password); err != nil {
+		return api.UserResponse{}, err
 	}
 
+	return api.UserResponse{Success: true, User: toUserData(&user)}, nil
+}
+
+func setTokenCookie(w http.ResponseWriter, token string, expires time.Time) {
 	http.SetCookie(w, &http.Cookie{
 		Path:     "/",
 		Name:     "token",
 		Value:    token,
 		Secure:   true,
 		SameSite: http.SameSiteDefaultMode,
-		Expires:  time.Now().Add(24 * time.Hour),
+		Expires:  expires,
 	})
-
-	utils.SendJSON(w, http.StatusOK, response)
 }
 
-func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+func clearTokenCookie(w http.ResponseWriter) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     "token",
 		Value:    "",
@@ -139,13 +227,4 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 		HttpOnly: true,
 		Path:     "/",
 	})
-
-	res := api.SuccessResponse{
-		Success: true,
-		Message: "Logged out successfully.",
-	}
-
-	utils.SendJSON(w, http.StatusOK, res)
 }
-
-func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {}
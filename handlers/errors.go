@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/kenztech/go-api-starter/apperror"
+	"github.com/kenztech/go-api-starter/i18n"
+	"github.com/kenztech/go-api-starter/models"
+	"github.com/kenztech/go-api-starter/models/api"
+)
+
+// errNoUserData means a handler behind Authenticate found no user in the
+// context, which is a routing mistake rather than a client error.
+var errNoUserData = errors.New("no user data in request context")
+
+// errNoTenant means a handler behind Tenancy.Resolve found no tenant in the
+// context
+var errNoTenant = errors.New("no tenant in request context")
+
+var (
+	errNotImpersonating  = apperror.New(http.StatusBadRequest, apperror.CodeNotImpersonating, "Not impersonating")
+	errImpersonateSelf   = apperror.New(http.StatusBadRequest, apperror.CodeCannotImpersonateSelf, "Cannot impersonate yourself")
+	errImpersonateAdmin  = apperror.New(http.StatusForbidden, apperror.CodeCannotImpersonateAdmin, "Admins cannot be impersonated")
+	errInvalidResetToken = apperror.New(http.StatusBadRequest, apperror.CodeInvalidResetToken, "Invalid or expired reset token")
+	errInvalidSetupToken = apperror.New(http.StatusUnauthorized, apperror.CodeInvalidSetupToken, "Invalid or expired setup token")
+	errSetupCompleted    = apperror.New(http.StatusConflict, apperror.CodeSetupCompleted, "Setup has already been completed")
+
+	errPasswordReused = apperror.Validation([]api.FieldError{{
+		Field:   "password",
+		Rule:    "not_reused",
+		Message: i18n.RuleMessage(i18n.Default(), "not_reused", "password", ""),
+	}})
+
+	errInvalidSort = apperror.Validation([]api.FieldError{{
+		Field:   "sort",
+		Rule:    "oneof",
+		Param:   sortFields,
+		Message: i18n.RuleMessage(i18n.Default(), "oneof", "sort", sortFields),
+	}})
+)
+
+var sortFields = strings.Join(models.UserSortFields, " ")
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/kenztech/go-api-starter/apperror"
+	"github.com/kenztech/go-api-starter/models"
+	"github.com/kenztech/go-api-starter/models/api"
+	"github.com/kenztech/go-api-starter/tenant"
+	"github.com/kenztech/go-api-starter/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type OrganizationHandler struct {
+	orgs  models.OrganizationRepository
+	users models.UserRepository
+}
+
+func NewOrganizationHandler(orgs models.OrganizationRepository, users models.UserRepository) *OrganizationHandler {
+	return &OrganizationHandler{orgs, users}
+}
+
+// GetOrganizations lists the organizations the user is a member of, or all
+// of them for admins
+func (h *OrganizationHandler) GetOrganizations(r *http.Request) (api.OrganizationsResponse, error) {
+	data, ok := utils.GetUserDataFromContext(r.Context())
+	if !ok {
+		return api.OrganizationsResponse{}, apperror.Internal(errNoUserData)
+	}
+
+	userID, err := utils.ParseID(data.ID)
+	if err != nil {
+		return api.OrganizationsResponse{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var ids []bson.ObjectID
+	roles := map[bson.ObjectID]string{}
+	if data.Role != "admin" {
+		memberships, err := h.orgs.UserMemberships(ctx, userID)
+		if err != nil {
+			return api.OrganizationsResponse{}, err
+		}
+		ids = []bson.ObjectID{}
+		for _, m := range memberships {
+			ids = append(ids, m.OrgID)
+			roles[m.OrgID] = m.Role
+		}
+	}
+
+	orgs, err := h.orgs.List(ctx, ids)
+	if err != nil {
+		return api.OrganizationsResponse{}, err
+	}
+
+	response := api.OrganizationsResponse{Success: true, Organizations: make([]api.OrganizationData, len(orgs))}
+	for i := range orgs {
+		role := roles[orgs[i].ID]
+		if data.Role == "admin" {
+			role = "admin"
+		}
+		response.Organizations[i] = toOrganizationData(&orgs[i], role)
+	}
+	return response, nil
+}
+
+func (h *OrganizationHandler) CreateOrganization(r *http.Request) (api.OrganizationResponse, error) {
+	var request api.OrganizationRequest
+	if err := utils.DecodeJSON(r, &request); err != nil {
+		return api.OrganizationResponse{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	org := models.Organization{Name: request.Name, Slug: request.Slug}
+	if err := h.orgs.Create(ctx, &org); err != nil {
+		return api.OrganizationResponse{}, organizationError(err)
+	}
+
+	return api.OrganizationResponse{Success: true, Organization: toOrganizationData(&org, "admin")}, nil
+}
+
+// GetOrganization returns the request's organization
+func (h *OrganizationHandler) GetOrganization(r *http.Request) (api.OrganizationResponse, error) {
+	t, ok := tenant.FromContext(r.Context())
+	if !ok {
+		return api.OrganizationResponse{}, apperror.Internal(errNoTenant)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	org, err := h.orgs.FindByID(ctx, t.OrgID)
+	if err != nil {
+		return api.OrganizationResponse{}, organizationError(err)
+	}
+
+	return api.OrganizationResponse{Success: true, Organization: toOrganizationData(org, t.Role)}, nil
+}
+
+// GetMembers lists the members of the request's organization
+func (h *OrganizationHandler) GetMembers(r *http.Request) (api.MembersResponse, error) {
+	t, ok := tenant.FromContext(r.Context())
+	if !ok {
+		return api.MembersResponse{}, apperror.Internal(errNoTenant)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	memberships, err := h.orgs.Members(ctx, t.OrgID)
+	if err != nil {
+		return api.MembersResponse{}, err
+	}
+
+	response := api.MembersResponse{Success: true, Members: []api.MemberData{}}
+	for i := range memberships {
+		user, err := h.users.FindByID(ctx, memberships[i].UserID)
+		if errors.Is(err, models.ErrUserNotFound) {
+			// Deleted users keep their memberships until they are restored.
+			continue
+		}
+		if err != nil {
+			return api.MembersResponse{}, err
+		}
+		response.Members = append(response.Members, toMemberData(&memberships[i], user))
+	}
+	return response, nil
+}
+
+// AddMember adds the user with the given email to the request's
+// organization, or changes their role if they already are a member. It is
+// for admins: users are global, and the answer tells whether the email is
+// registered.
+func (h *OrganizationHandler) AddMember(r *http.Request) (api.MemberResponse, error) {
+	t, ok := tenant.FromContext(r.Context())
+	if !ok {
+		return api.MemberResponse{}, apperror.Internal(errNoTenant)
+	}
+
+	var request api.AddMemberRequest
+	if err := utils.DecodeJSON(r, &request); err != nil {
+		return api.MemberResponse{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.users.FindByEmail(ctx, request.Email)
+	if err != nil {
+		return api.MemberResponse{}, userError(err)
+	}
+
+	membership := models.Membership{OrgID: t.OrgID, UserID: user.ID, Role: request.Role}
+	if err := h.orgs.SetMember(ctx, &membership); err != nil {
+		return api.MemberResponse{}, err
+	}
+
+	return api.MemberResponse{Success: true, Member: toMemberData(&membership, user)}, nil
+}
+
+// SetMember changes the role of a member of the request's organization.
+// Users who aren't members yet are added by email with AddMember: their ID
+// alone must not reveal their profile.
+func (h *OrganizationHandler) SetMember(r *http.Request) (api.MemberResponse, error) {
+	t, ok := tenant.FromContext(r.Context())
+	if !ok {
+		return api.MemberResponse{}, apperror.Internal(errNoTenant)
+	}
+
+	userID, err := utils.URLParamID(r, "user_id")
+	if err != nil {
+		return api.MemberResponse{}, err
+	}
+
+	var request api.MemberRequest
+	if err := utils.DecodeJSON(r, &request); err != nil {
+		return api.MemberResponse{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	membership, err := h.orgs.FindMembership(ctx, t.OrgID, userID)
+	if errors.Is(err, models.ErrNotMember) {
+		return api.MemberResponse{}, apperror.ErrNotFound
+	}
+	if err != nil {
+		return api.MemberResponse{}, err
+	}
+
+	user, err := h.users.FindByID(ctx, userID)
+	if err != nil {
+		return api.MemberResponse{}, userError(err)
+	}
+
+	membership.Role = request.Role
+	if err := h.orgs.SetMember(ctx, membership); err != nil {
+		return api.MemberResponse{}, err
+	}
+
+	return api.MemberResponse{Success: true, Member: toMemberData(membership, user)}, nil
+}
+
+// RemoveMember removes a user from the request's organization
+func (h *OrganizationHandler) RemoveMember(r *http.Request) (api.SuccessResponse, error) {
+	t, ok := tenant.FromContext(r.Context())
+	if !ok {
+		return api.SuccessResponse{}, apperror.Internal(errNoTenant)
+	}
+
+	userID, err := utils.URLParamID(r, "user_id")
+	if err != nil {
+		return api.SuccessResponse{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	err = h.orgs.RemoveMember(ctx, t.OrgID, userID)
+	if errors.Is(err, models.ErrNotMember) {
+		return api.SuccessResponse{}, apperror.ErrNotFound
+	}
+	if err != nil {
+		return api.SuccessResponse{}, err
+	}
+
+	return api.SuccessResponse{
+		Success: true,
+		Message: "Member removed.",
+	}, nil
+}
+
+// organizationError turns repository errors into errors for clients
+func organizationError(err error) error {
+	switch {
+	case errors.Is(err, models.ErrOrganizationNotFound):
+		return apperror.ErrOrganizationNotFound
+	case errors.Is(err, models.ErrOrganizationExists):
+		return apperror.ErrOrganizationExists
+	case errors.Is(err, models.ErrNotMember):
+		return apperror.ErrNotMember
+	}
+	return err
+}
+
+func toOrganizationData(org *models.Organization, role string) api.OrganizationData {
+	return api.OrganizationData{
+		ID:        org.ID.Hex(),
+		Name:      org.Name,
+		Slug:      org.Slug,
+		Role:      role,
+		CreatedAt: org.CreatedAt,
+	}
+}
+
+func toMemberData(membership *models.Membership, user *models.User) api.MemberData {
+	return api.MemberData{
+		UserID:    user.ID.Hex(),
+		Name:      user.Name,
+		Username:  user.Username,
+		Email:     user.Email,
+		Role:      membership.Role,
+		CreatedAt: membership.CreatedAt,
+	}
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/kenztech/go-api-starter/apperror"
+	"github.com/kenztech/go-api-starter/logging"
+	"github.com/kenztech/go-api-starter/models"
+	"github.com/kenztech/go-api-starter/models/api"
+	"github.com/kenztech/go-api-starter/utils"
+)
+
+// ChangePassword updates the password of the logged in user
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) error {
+	data, ok := utils.GetUserDataFromContext(r.Context())
+	if !ok {
+		return apperror.Internal(errNoUserData)
+	}
+
+	var request api.ChangePasswordRequest
+	if err := utils.DecodeJSON(r, &request); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	id, err := utils.ParseID(data.ID)
+	if err != nil {
+		return err
+	}
+
+	user, err := h.users.FindByID(ctx, id)
+	if err != nil {
+		return userError(err)
+	}
+
+	if !utils.ComparePassword(user.Password, request.CurrentPassword) {
+		return apperror.ErrInvalidCredentials
+	}
+
+	if err := setPassword(ctx, h.users, h.policy, user, request.NewPassword); err != nil {
+		return err
+	}
+
+	// Changing the password revoked every session: reissue this one, which
+	// also lifts a pending forced password change.
+	if !data.IsImpersonated() {
+		token, err := h.tokens.GenerateRefreshToken(user.ID.Hex(), user.Email, user.Role, data.OrgID, false)
+		if err != nil {
+			return err
+		}
+		setTokenCookie(w, token, time.Now().Add(h.tokens.SessionTTL()))
+	}
+
+	utils.SendJSON(w, http.StatusOK, api.SuccessResponse{
+		Success: true,
+		Message: "Password changed successfully.",
+	})
+	return nil
+}
+
+// ForgotPassword emails a password reset link. It always reports success so
+// it cannot be used to find out which emails are registered.
+func (h *AuthHandler) ForgotPassword(r *http.Request) (api.SuccessResponse, error) {
+	var request api.ForgotPasswordRequest
+	if err := utils.DecodeJSON(r, &request); err != nil {
+		return api.SuccessResponse{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.users.FindByEmail(ctx, request.Email)
+	if err == nil {
+		if err := h.sendResetEmail(ctx, user); err != nil {
+			logging.FromContext(ctx).Error("Error sending reset email", "error", err)
+		}
+	} else if !errors.Is(err, models.ErrUserNotFound) {
+		logging.FromContext(ctx).Error("Error finding user", "error", err)
+	}
+
+	return api.SuccessResponse{
+		Success: true,
+		Message: "If the email is registered, a reset link has been sent.",
+	}, nil
+}
+
+// ResetPassword sets a new password using a token from ForgotPassword
+func (h *AuthHandler) ResetPassword(r *http.Request) (api.SuccessResponse, error) {
+	var request api.ResetPasswordRequest
+	if err := utils.DecodeJSON(r, &request); err != nil {
+		return api.SuccessResponse{}, err
+	}
+
+	token, err := h.tokens.ValidateResetToken(request.Token)
+	if err != nil {
+		return api.SuccessResponse{}, errInvalidResetToken
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.users.FindByEmail(ctx, token.Email)
+	if err != nil {
+		return api.SuccessResponse{}, errInvalidResetToken.Wrap(err)
+	}
+	// Tokens are bound to the password they were issued for: once used, or
+	// once the password changed otherwise, they are rejected.
+	if !h.tokens.ResetTokenMatches(token, user.Password) {
+		return api.SuccessResponse{}, errInvalidResetToken
+	}
+
+	if err := setPassword(ctx, h.users, h.policy, user, request.Password); err != nil {
+		return api.SuccessResponse{}, err
+	}
+
+	return api.SuccessResponse{
+		Success: true,
+		Message: "Password has been reset.",
+	}, nil
+}
+
+// setPassword checks the new password against the policy and the user's
+// password history, then stores it.
+func setPassword(ctx context.Context, users models.UserRepository, policy *utils.PasswordPolicy, user *models.User, password string) error {
+	if err := policy.Validate(password, user.Email, user.Username); err != nil {
+		return err
+	}
+
+	if policy.IsReused(password, user.Password, user.PasswordHistory) {
+		return errPasswordReused
+	}
+
+	history := policy.NextHistory(user.Password, user.PasswordHistory)
+	if err := models.SetPassword(ctx, users, user, password, history, false); err != nil {
+		return fmt.Errorf("updating password: %w", err)
+	}
+	return nil
+}
+
+func (h *AuthHandler) sendResetEmail(ctx context.Context, user *models.User) error {
+	token, err := h.tokens.GenerateResetToken(user.Email, user.Password)
+	if err != nil {
+		return err
+	}
+
+	link := h.cfg.Mail.ResetPasswordURL + "?token=" + url.QueryEscape(token)
+	message := fmt.Sprintf("Use the link below to reset your password. It expires in %s.\n\n%s", h.cfg.JWT.ResetTTL, link)
+
+	return h.mailer.SendMail(ctx, user.Email, "Reset your password", message)
+}
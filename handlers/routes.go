@@ -1,32 +1,81 @@
 package handlers
 
 import (
+	"net/http"
+
 	"github.com/go-chi/chi/v5"
+	"github.com/kenztech/go-api-starter/config"
 	"github.com/kenztech/go-api-starter/middlewares"
+	"github.com/kenztech/go-api-starter/models"
+	"github.com/kenztech/go-api-starter/utils"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
-func InitRoutes(r *chi.Mux, db *mongo.Database) {
-	authHandler := NewAuthHandler(db)
-	userHandler := NewUserHandler(db)
+func InitRoutes(r *chi.Mux, db *mongo.Database, cfg *config.Config, policy *utils.PasswordPolicy, tokens *utils.TokenManager, mailer *utils.EmailSender) {
+	users := models.NewMongoUserRepository(db)
+	orgs := models.NewMongoOrganizationRepository(db)
+	audit := models.NewMongoAuditRecorder(db)
+
+	authHandler := NewAuthHandler(users, orgs, audit, cfg, policy, tokens, mailer)
+	userHandler := NewUserHandler(users, audit, cfg, policy, tokens)
+	organizationHandler := NewOrganizationHandler(orgs, users)
+	setupHandler := NewSetupHandler(db, users, policy)
+	auth := middlewares.NewAuthenticator(users, tokens)
+	tenancy := middlewares.NewTenancy(orgs, cfg.Tenancy.Domain)
+	limiter := middlewares.NewRateLimiter(cfg.RateLimit)
+	idempotency := middlewares.NewIdempotency(models.NewMongoIdempotencyStore(db), cfg.Idempotency.TTL)
 
 	r.Route("/api", func(r chi.Router) {
+		r.With(limiter.Limit).Post("/setup", utils.JSON(http.StatusCreated, setupHandler.Setup))
+
 		r.Route("/auth", func(r chi.Router) {
-			r.Post("/login", authHandler.Login)
-			r.Post("/register", authHandler.Register)
-			r.With(middlewares.Authenticate).Get("/me", authHandler.Me)
-			r.With(middlewares.Authenticate).Post("/logout", authHandler.Logout)
+			r.With(limiter.Limit).Post("/login", utils.Handle(authHandler.Login))
+			r.With(limiter.Limit, idempotency.Handle).Post("/register", utils.JSON(http.StatusCreated, authHandler.Register))
+			r.With(limiter.Limit).Post("/forgot-password", utils.JSON(http.StatusOK, authHandler.ForgotPassword))
+			r.With(limiter.Limit).Post("/reset-password", utils.JSON(http.StatusOK, authHandler.ResetPassword))
+			r.With(auth.Authenticate).Get("/me", utils.JSON(http.StatusOK, authHandler.Me))
+			r.With(auth.Authenticate).Post("/logout", authHandler.Logout)
+			r.With(auth.Authenticate).Post("/impersonation/stop", utils.Handle(authHandler.StopImpersonation))
+			r.With(auth.Authenticate, middlewares.NoImpersonation).Post("/password", utils.Handle(authHandler.ChangePassword))
 		})
 
 		r.Route("/users", func(r chi.Router) {
-			r.Use(middlewares.Authenticate)
+			r.Use(auth.Authenticate)
 			r.Use(middlewares.AdminOnly)
+			r.Use(middlewares.PasswordChanged)
+
+			r.Get("/", utils.JSON(http.StatusOK, userHandler.GetUsers))
+			r.Get("/{id}", utils.JSON(http.StatusOK, userHandler.GetUser))
+			r.With(idempotency.Handle).Post("/", utils.JSON(http.StatusCreated, userHandler.CreateUser))
+			r.Put("/{id}", utils.JSON(http.StatusOK, userHandler.UpdateUser))
+			r.Delete("/{id}", utils.JSON(http.StatusOK, userHandler.DeleteUser))
+			r.Post("/{id}/restore", utils.JSON(http.StatusOK, userHandler.RestoreUser))
+			r.With(middlewares.NoImpersonation).Post("/{id}/impersonate", utils.Handle(userHandler.Impersonate))
+		})
+
+		r.Route("/organizations", func(r chi.Router) {
+			r.Use(auth.Authenticate)
+			r.Use(middlewares.PasswordChanged)
+
+			r.Get("/", utils.JSON(http.StatusOK, organizationHandler.GetOrganizations))
+			r.With(middlewares.AdminOnly).Post("/", utils.JSON(http.StatusCreated, organizationHandler.CreateOrganization))
+		})
+
+		// The organization the request acts for, picked by Tenancy.Resolve
+		r.Route("/organization", func(r chi.Router) {
+			r.Use(auth.Authenticate)
+			r.Use(middlewares.PasswordChanged)
+			r.Use(tenancy.Resolve)
+
+			// Only the organization's admins manage its members. Adding one
+			// looks the user up among all users, which only admins may do.
+			manageMembers := middlewares.Permissions{}.Require("organization.members.manage")
 
-			r.Get("/", userHandler.GetUsers)
-			r.Get("/{id}", userHandler.GetUser)
-			r.Post("/", userHandler.CreateUser)
-			r.Put("/{id}", userHandler.UpdateUser)
-			r.Delete("/{id}", userHandler.DeleteUser)
+			r.Get("/", utils.JSON(http.StatusOK, organizationHandler.GetOrganization))
+			r.Get("/members", utils.JSON(http.StatusOK, organizationHandler.GetMembers))
+			r.With(middlewares.AdminOnly).Post("/members", utils.JSON(http.StatusOK, organizationHandler.AddMember))
+			r.With(manageMembers).Put("/members/{user_id}", utils.JSON(http.StatusOK, organizationHandler.SetMember))
+			r.With(manageMembers).Delete("/members/{user_id}", utils.JSON(http.StatusOK, organizationHandler.RemoveMember))
 		})
 	})
 }
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kenztech/go-api-starter/logging"
+	"github.com/kenztech/go-api-starter/models"
+	"github.com/kenztech/go-api-starter/models/api"
+	"github.com/kenztech/go-api-starter/utils"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type SetupHandler struct {
+	db     *mongo.Database
+	users  models.UserRepository
+	policy *utils.PasswordPolicy
+}
+
+// NewSetupHandler needs the database for the setup tokens
+func NewSetupHandler(db *mongo.Database, users models.UserRepository, policy *utils.PasswordPolicy) *SetupHandler {
+	return &SetupHandler{db, users, policy}
+}
+
+// Setup creates the first admin using the one-time setup token printed at startup
+func (h *SetupHandler) Setup(r *http.Request) (api.UserResponse, error) {
+	var request api.SetupRequest
+	if err := utils.DecodeJSON(r, &request); err != nil {
+		return api.UserResponse{}, err
+	}
+
+	if err := h.policy.Validate(request.Password, request.Email, request.Username); err != nil {
+		return api.UserResponse{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	exists, err := models.AdminExists(ctx, h.users)
+	if err != nil {
+		return api.UserResponse{}, fmt.Errorf("checking for admin user: %w", err)
+	}
+	if exists {
+		return api.UserResponse{}, errSetupCompleted
+	}
+
+	token, err := models.ConsumeSetupToken(ctx, h.db, request.Token)
+	if err != nil {
+		return api.UserResponse{}, fmt.Errorf("consuming setup token: %w", err)
+	}
+	if token == nil {
+		return api.UserResponse{}, errInvalidSetupToken
+	}
+
+	user := models.User{
+		Name:     request.Name,
+		Role:     "admin",
+		Email:    request.Email,
+		Status:   "active",
+		Username: request.Username,
+	}
+
+	if err := insertUser(ctx, h.users, &user, request.Password); err != nil {
+		// The token is consumed first so that two requests cannot both use
+		// it; give it back when no admin came out of it, e.g. because the
+		// email is taken.
+		restoreCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if restoreErr := models.RestoreSetupToken(restoreCtx, h.db, token); restoreErr != nil {
+			logging.FromContext(ctx).Error("Error restoring setup token", "error", restoreErr)
+		}
+		return api.UserResponse{}, err
+	}
+
+	logging.FromContext(ctx).Info("Admin user created through setup", "email", user.Email)
+
+	return api.UserResponse{Success: true, User: toUserData(&user)}, nil
+}
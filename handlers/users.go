@@ -1,21 +1,353 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
-	"go.mongodb.org/mongo-driver/v2/mongo"
+	"github.com/kenztech/go-api-starter/apperror"
+	"github.com/kenztech/go-api-starter/config"
+	"github.com/kenztech/go-api-starter/models"
+	"github.com/kenztech/go-api-starter/models/api"
+	"github.com/kenztech/go-api-starter/utils"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
 )
 
 type UserHandler struct {
-	db *mongo.Database
+	users  models.UserRepository
+	audit  models.AuditRecorder
+	cfg    *config.Config
+	policy *utils.PasswordPolicy
+	tokens *utils.TokenManager
+}
+
+func NewUserHandler(users models.UserRepository, audit models.AuditRecorder, cfg *config.Config, policy *utils.PasswordPolicy, tokens *utils.TokenManager) *UserHandler {
+	return &UserHandler{users, audit, cfg, policy, tokens}
 }
 
-func NewUserHandler(db *mongo.Database) *UserHandler {
-	return &UserHandler{db}
+// usersQuery holds the filters of GetUsers that must be validated
+type usersQuery struct {
+	Role   string `json:"role" validate:"omitempty,oneof=admin merchant operator"`
+	Status string `json:"status" validate:"omitempty,oneof=active inactive banned"`
+}
+
+func (h *UserHandler) GetUser(r *http.Request) (api.UserResponse, error) {
+	id, err := utils.URLParamID(r, "id")
+	if err != nil {
+		return api.UserResponse{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.users.FindByID(ctx, id)
+	if err != nil {
+		return api.UserResponse{}, userError(err)
+	}
+
+	return api.UserResponse{Success: true, User: toUserData(user)}, nil
 }
 
-func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request)    {}
-func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request)   {}
-func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {}
-func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {}
-func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {}
+// GetUsers lists users a page at a time. Query parameters: page, size, sort
+// (a field, "-" prefixed for descending order), role, status, search and
+// deleted, which lists the deleted users instead.
+func (h *UserHandler) GetUsers(r *http.Request) (api.UsersResponse, error) {
+	query := r.URL.Query()
+	filter := usersQuery{Role: query.Get("role"), Status: query.Get("status")}
+	if err := utils.Validate(filter); err != nil {
+		return api.UsersResponse{}, err
+	}
+
+	opts := models.ListOptions{
+		Filter: models.UserFilter{
+			Role:    filter.Role,
+			Status:  filter.Status,
+			Search:  query.Get("search"),
+			Deleted: query.Get("deleted") == "true",
+		},
+		Sort: query.Get("sort"),
+		Page: queryInt(r, "page", 1),
+		Size: queryInt(r, "size", defaultPageSize),
+	}
+	if opts.Page < 1 {
+		opts.Page = 1
+	}
+	if opts.Size < 1 {
+		opts.Size = defaultPageSize
+	} else if opts.Size > maxPageSize {
+		opts.Size = maxPageSize
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	users, err := h.users.List(ctx, opts)
+	if errors.Is(err, models.ErrInvalidSort) {
+		return api.UsersResponse{}, errInvalidSort
+	}
+	if err != nil {
+		return api.UsersResponse{}, err
+	}
+
+	total, err := h.users.Count(ctx, opts.Filter)
+	if err != nil {
+		return api.UsersResponse{}, err
+	}
+
+	response := api.UsersResponse{
+		Success: true,
+		Users:   make([]api.UserData, len(users)),
+		Pagination: api.Pagination{
+			Page:       opts.Page,
+			Size:       opts.Size,
+			TotalCount: total,
+			TotalPages: int((total + int64(opts.Size) - 1) / int64(opts.Size)),
+		},
+	}
+	for i := range users {
+		response.Users[i] = toUserData(&users[i])
+	}
+
+	return response, nil
+}
+
+func (h *UserHandler) CreateUser(r *http.Request) (api.UserResponse, error) {
+	var request api.UserRequest
+	if err := utils.DecodeJSON(r, &request); err != nil {
+		return api.UserResponse{}, err
+	}
+
+	if err := h.policy.Validate(request.Password, request.Email, request.Username); err != nil {
+		return api.UserResponse{}, err
+	}
+
+	user := models.User{
+		Name:     request.Name,
+		Role:     request.Role,
+		Email:    request.Email,
+		Status:   request.Status,
+		Username: request.Username,
+	}
+
+	if err := insertUser(r.Context(), h.users, &user, request.Password); err != nil {
+		return api.UserResponse{}, err
+	}
+
+	return api.UserResponse{Success: true, User: toUserData(&user)}, nil
+}
+
+// UpdateUser changes the fields present in the request. The request must
+// carry the user's ETag in If-Match. Changing the role or status revokes the
+// user's tokens, since sessions carry both.
+func (h *UserHandler) UpdateUser(r *http.Request) (api.UserResponse, error) {
+	id, err := utils.URLParamID(r, "id")
+	if err != nil {
+		return api.UserResponse{}, err
+	}
+
+	var request api.UpdateUserRequest
+	if err := utils.DecodeJSON(r, &request); err != nil {
+		return api.UserResponse{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.users.FindByID(ctx, id)
+	if err != nil {
+		return api.UserResponse{}, userError(err)
+	}
+
+	if err := utils.CheckIfMatch(r, toUserData(user).ETag()); err != nil {
+		return api.UserResponse{}, err
+	}
+	if request.Version != nil && *request.Version != user.Version {
+		return api.UserResponse{}, apperror.ErrVersionConflict
+	}
+
+	role, status := user.Role, user.Status
+	if request.Name != nil {
+		user.Name = *request.Name
+	}
+	if request.Username != nil {
+		user.Username = *request.Username
+	}
+	if request.Email != nil {
+		user.Email = *request.Email
+	}
+	if request.Role != nil {
+		user.Role = *request.Role
+	}
+	if request.Status != nil {
+		user.Status = *request.Status
+	}
+
+	if user.Role != role || user.Status != status {
+		err = models.RevokeTokens(ctx, h.users, user)
+	} else {
+		err = h.users.Update(ctx, user)
+	}
+	if err != nil {
+		return api.UserResponse{}, userError(err)
+	}
+
+	return api.UserResponse{Success: true, User: toUserData(user)}, nil
+}
+
+// DeleteUser soft deletes a user. The request must carry the user's ETag in
+// If-Match.
+func (h *UserHandler) DeleteUser(r *http.Request) (api.SuccessResponse, error) {
+	id, err := utils.URLParamID(r, "id")
+	if err != nil {
+		return api.SuccessResponse{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.users.FindByID(ctx, id)
+	if err != nil {
+		return api.SuccessResponse{}, userError(err)
+	}
+	if err := utils.CheckIfMatch(r, toUserData(user).ETag()); err != nil {
+		return api.SuccessResponse{}, err
+	}
+
+	if err := h.users.Delete(ctx, user); err != nil {
+		return api.SuccessResponse{}, userError(err)
+	}
+
+	return api.SuccessResponse{
+		Success: true,
+		Message: "User deleted.",
+	}, nil
+}
+
+// RestoreUser brings back a deleted user
+func (h *UserHandler) RestoreUser(r *http.Request) (api.UserResponse, error) {
+	id, err := utils.URLParamID(r, "id")
+	if err != nil {
+		return api.UserResponse{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.users.Restore(ctx, id)
+	if err != nil {
+		return api.UserResponse{}, userError(err)
+	}
+
+	return api.UserResponse{Success: true, User: toUserData(user)}, nil
+}
+
+// Impersonate issues a time-boxed token that lets an admin act as another user
+func (h *UserHandler) Impersonate(w http.ResponseWriter, r *http.Request) error {
+	actor, ok := utils.GetUserDataFromContext(r.Context())
+	if !ok {
+		return apperror.Internal(errNoUserData)
+	}
+
+	id, err := utils.URLParamID(r, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.users.FindByID(ctx, id)
+	if err != nil {
+		return userError(err)
+	}
+
+	if user.Role == "admin" {
+		return errImpersonateAdmin
+	}
+	if user.Email == actor.Email {
+		return errImpersonateSelf
+	}
+
+	ttl := h.cfg.JWT.ImpersonationTTL
+	token, err := h.tokens.GenerateImpersonationToken(user.ID.Hex(), user.Email, user.Role, actor.Email, ttl)
+	if err != nil {
+		return err
+	}
+
+	// The audit entry is mandatory: refuse to impersonate if it cannot be recorded.
+	err = h.audit.Record(ctx, models.AuditLog{
+		Action:      models.AuditImpersonationStart,
+		ActorEmail:  actor.Email,
+		TargetEmail: user.Email,
+		IPAddress:   r.RemoteAddr,
+		UserAgent:   r.UserAgent(),
+	})
+	if err != nil {
+		return fmt.Errorf("recording impersonation audit: %w", err)
+	}
+
+	setTokenCookie(w, token, time.Now().Add(ttl))
+
+	utils.SendJSON(w, http.StatusOK, api.UserResponse{Success: true, User: toUserData(user)})
+	return nil
+}
+
+// insertUser stores a new user, reporting duplicates as a conflict
+func insertUser(ctx context.Context, users models.UserRepository, user *models.User, password string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := models.CreateUser(ctx, users, user, password); err != nil {
+		return userError(err)
+	}
+	return nil
+}
+
+// userError turns repository errors into errors for clients
+func userError(err error) error {
+	switch {
+	case errors.Is(err, models.ErrUserNotFound):
+		return apperror.ErrUserNotFound
+	case errors.Is(err, models.ErrUserExists):
+		return apperror.ErrUserExists
+	case errors.Is(err, models.ErrVersionConflict):
+		return apperror.ErrVersionConflict.Wrap(err)
+	}
+	return err
+}
+
+// queryInt returns an integer query parameter, or def when it is missing or
+// not a number
+func queryInt(r *http.Request, name string, def int) int {
+	n, err := strconv.Atoi(r.URL.Query().Get(name))
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+func toUserData(user *models.User) api.UserData {
+	data := api.UserData{
+		ID:                 user.ID.Hex(),
+		Name:               user.Name,
+		Role:               user.Role,
+		Email:              user.Email,
+		Status:             user.Status,
+		Username:           user.Username,
+		MustChangePassword: user.MustChangePassword,
+		CreatedAt:          user.CreatedAt,
+		UpdatedAt:          user.UpdatedAt,
+		Version:            user.Version,
+	}
+	if !user.DeletedAt.IsZero() {
+		data.DeletedAt = &user.DeletedAt
+	}
+	return data
+}
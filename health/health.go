@@ -0,0 +1,122 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kenztech/go-api-starter/models/api"
+	"github.com/kenztech/go-api-starter/utils"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// HealthCheck reports whether a dependency is usable. Check must return
+// promptly once ctx is done.
+type HealthCheck interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type checkFunc struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+func (c checkFunc) Name() string                    { return c.name }
+func (c checkFunc) Check(ctx context.Context) error { return c.check(ctx) }
+
+// NewCheck builds a HealthCheck from a function
+func NewCheck(name string, check func(ctx context.Context) error) HealthCheck {
+	return checkFunc{name, check}
+}
+
+// Registry runs the registered checks for the readiness endpoint
+type Registry struct {
+	timeout time.Duration
+
+	mu     sync.RWMutex
+	checks []HealthCheck
+}
+
+// NewRegistry returns a registry giving each check at most timeout to answer
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{timeout: timeout}
+}
+
+// Register adds a check to the readiness endpoint
+func (reg *Registry) Register(check HealthCheck) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.checks = append(reg.checks, check)
+}
+
+// Liveness reports that the process is up. It never checks dependencies, so
+// an unreachable database does not get the process restarted.
+func (reg *Registry) Liveness(w http.ResponseWriter, r *http.Request) {
+	utils.SendJSON(w, http.StatusOK, api.HealthResponse{Status: StatusOK})
+}
+
+// Readiness runs every check concurrently and reports 503 if any fails
+func (reg *Registry) Readiness(w http.ResponseWriter, r *http.Request) {
+	reg.mu.RLock()
+	checks := make([]HealthCheck, len(reg.checks))
+	copy(checks, reg.checks)
+	reg.mu.RUnlock()
+
+	results := make([]api.CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+			results[i] = reg.run(r.Context(), check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	response := api.HealthResponse{Status: StatusOK, Checks: make(map[string]api.CheckResult, len(checks))}
+	for i, check := range checks {
+		response.Checks[check.Name()] = results[i]
+		if results[i].Status != StatusOK {
+			response.Status = StatusUnavailable
+		}
+	}
+
+	status := http.StatusOK
+	if response.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	utils.SendJSON(w, status, response)
+}
+
+func (reg *Registry) run(ctx context.Context, check HealthCheck) api.CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, reg.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() { errCh <- check.Check(ctx) }()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := api.CheckResult{
+		Status:    StatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusUnavailable
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,63 @@
+package i18n
+
+import (
+	"github.com/go-playground/locales/de"
+	"github.com/kenztech/go-api-starter/apperror"
+)
+
+var german = catalog{
+	locale: de.New(),
+	rules: map[string]string{
+		"required":         "{0} ist erforderlich",
+		"required_without": "{0} ist erforderlich, wenn {1} fehlt",
+		"email":            "{0} muss eine gültige E-Mail-Adresse sein",
+		"min":              "{0} muss mindestens {1} Zeichen lang sein",
+		"gte":              "{0} muss mindestens {1} sein",
+		"alphanum":         "{0} darf nur Buchstaben und Ziffern enthalten",
+		"oneof":            "{0} muss einer der folgenden Werte sein: {1}",
+		"max_bytes":        "{0} darf höchstens {1} Bytes lang sein",
+		"uppercase":        "{0} muss einen Großbuchstaben enthalten",
+		"lowercase":        "{0} muss einen Kleinbuchstaben enthalten",
+		"digit":            "{0} muss eine Ziffer enthalten",
+		"symbol":           "{0} muss ein Sonderzeichen enthalten",
+		"no_identifiers":   "{0} darf weder deine E-Mail-Adresse noch deinen Benutzernamen enthalten",
+		"not_breached":     "{0} ist in einem Datenleck aufgetaucht, bitte wähle ein anderes",
+		"not_reused":       "{0} darf keinem kürzlich verwendeten Passwort entsprechen",
+		"slug":             "{0} darf nur Kleinbuchstaben, Ziffern und Bindestriche enthalten, wie eine Subdomain",
+	},
+	codes: map[apperror.Code]string{
+		apperror.CodeInvalidRequest:         "Ungültiger Anfragetext",
+		apperror.CodeValidationFailed:       "Validierung fehlgeschlagen",
+		apperror.CodeInvalidID:              "Ungültige ID",
+		apperror.CodeUnauthorized:           "Nicht autorisiert",
+		apperror.CodeInvalidCredentials:     "Ungültige Anmeldedaten",
+		apperror.CodeInvalidResetToken:      "Ungültiger oder abgelaufener Token zum Zurücksetzen",
+		apperror.CodeInvalidSetupToken:      "Ungültiger oder abgelaufener Einrichtungstoken",
+		apperror.CodeForbidden:              "Zugriff verweigert",
+		apperror.CodeAccountInactive:        "Das Konto ist nicht aktiv",
+		apperror.CodePasswordChangeRequired: "Eine Passwortänderung ist erforderlich",
+		apperror.CodeImpersonationForbidden: "Aktion während einer Identitätsübernahme nicht erlaubt",
+		apperror.CodeCannotImpersonateSelf:  "Du kannst nicht deine eigene Identität übernehmen",
+		apperror.CodeCannotImpersonateAdmin: "Die Identität von Administratoren kann nicht übernommen werden",
+		apperror.CodeNotImpersonating:       "Keine aktive Identitätsübernahme",
+		apperror.CodeNotFound:               "Nicht gefunden",
+		apperror.CodeUserNotFound:           "Benutzer nicht gefunden",
+		apperror.CodeConflict:               "Konflikt",
+		apperror.CodeUserExists:             "E-Mail-Adresse oder Benutzername bereits vergeben",
+		apperror.CodeVersionConflict:        "Die Ressource wurde von jemand anderem geändert, bitte neu laden und erneut versuchen",
+		apperror.CodeSetupCompleted:         "Die Einrichtung wurde bereits abgeschlossen",
+		apperror.CodePreconditionFailed:     "If-Match entspricht nicht der aktuellen Version, bitte neu laden und erneut versuchen",
+		apperror.CodePreconditionRequired:   "Der If-Match-Header ist erforderlich",
+		apperror.CodeInvalidIdempotencyKey:  "Idempotency-Key muss 1 bis 255 Zeichen lang sein",
+		apperror.CodeIdempotencyInFlight:    "Eine Anfrage mit diesem Idempotency-Key wird noch verarbeitet",
+		apperror.CodeIdempotencyKeyReused:   "Dieser Idempotency-Key wurde für eine andere Anfrage verwendet",
+		apperror.CodeOrganizationRequired:   "Eine Organisation ist erforderlich: X-Organization senden oder die Subdomain der Organisation verwenden",
+		apperror.CodeOrganizationNotFound:   "Organisation nicht gefunden",
+		apperror.CodeOrganizationExists:     "Die Kennung der Organisation wird bereits verwendet",
+		apperror.CodeNotMember:              "Du bist kein Mitglied dieser Organisation",
+		apperror.CodeRateLimited:            "Zu viele Anfragen",
+		apperror.CodeRequestCanceled:        "Anfrage abgebrochen",
+		apperror.CodeInternal:               "Interner Serverfehler",
+		apperror.CodeTimeout:                "Die Anfrage hat zu lange gedauert, bitte versuche es erneut",
+	},
+}
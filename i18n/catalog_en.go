@@ -0,0 +1,27 @@
+package i18n
+
+import "github.com/go-playground/locales/en"
+
+// english only needs rule messages: errors are written in English, so their
+// own messages are used as they are.
+var english = catalog{
+	locale: en.New(),
+	rules: map[string]string{
+		"required":         "{0} is required",
+		"required_without": "{0} is required when {1} is missing",
+		"email":            "{0} must be a valid email address",
+		"min":              "{0} must be at least {1} characters long",
+		"gte":              "{0} must be {1} or greater",
+		"alphanum":         "{0} must contain only letters and digits",
+		"oneof":            "{0} must be one of: {1}",
+		"max_bytes":        "{0} must be at most {1} bytes long",
+		"uppercase":        "{0} must contain an uppercase letter",
+		"lowercase":        "{0} must contain a lowercase letter",
+		"digit":            "{0} must contain a digit",
+		"symbol":           "{0} must contain a symbol",
+		"no_identifiers":   "{0} must not contain your email or username",
+		"not_breached":     "{0} has appeared in a data breach, please choose another one",
+		"not_reused":       "{0} must not match a recently used password",
+		"slug":             "{0} must be lower case letters, digits and hyphens, like a subdomain",
+	},
+}
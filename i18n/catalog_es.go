@@ -0,0 +1,63 @@
+package i18n
+
+import (
+	"github.com/go-playground/locales/es"
+	"github.com/kenztech/go-api-starter/apperror"
+)
+
+var spanish = catalog{
+	locale: es.New(),
+	rules: map[string]string{
+		"required":         "{0} es obligatorio",
+		"required_without": "{0} es obligatorio si falta {1}",
+		"email":            "{0} debe ser una dirección de correo válida",
+		"min":              "{0} debe tener al menos {1} caracteres",
+		"gte":              "{0} debe ser mayor o igual que {1}",
+		"alphanum":         "{0} solo puede contener letras y dígitos",
+		"oneof":            "{0} debe ser uno de: {1}",
+		"max_bytes":        "{0} debe tener como máximo {1} bytes",
+		"uppercase":        "{0} debe contener una letra mayúscula",
+		"lowercase":        "{0} debe contener una letra minúscula",
+		"digit":            "{0} debe contener un dígito",
+		"symbol":           "{0} debe contener un símbolo",
+		"no_identifiers":   "{0} no debe contener tu correo ni tu nombre de usuario",
+		"not_breached":     "{0} ha aparecido en una filtración de datos, elige otra",
+		"not_reused":       "{0} no debe coincidir con una contraseña usada recientemente",
+		"slug":             "{0} solo puede contener minúsculas, dígitos y guiones, como un subdominio",
+	},
+	codes: map[apperror.Code]string{
+		apperror.CodeInvalidRequest:         "Cuerpo de la solicitud no válido",
+		apperror.CodeValidationFailed:       "La validación ha fallado",
+		apperror.CodeInvalidID:              "Identificador no válido",
+		apperror.CodeUnauthorized:           "No autorizado",
+		apperror.CodeInvalidCredentials:     "Credenciales no válidas",
+		apperror.CodeInvalidResetToken:      "Token de restablecimiento no válido o caducado",
+		apperror.CodeInvalidSetupToken:      "Token de configuración no válido o caducado",
+		apperror.CodeForbidden:              "Acceso prohibido",
+		apperror.CodeAccountInactive:        "La cuenta no está activa",
+		apperror.CodePasswordChangeRequired: "Es necesario cambiar la contraseña",
+		apperror.CodeImpersonationForbidden: "Acción no permitida durante una suplantación",
+		apperror.CodeCannotImpersonateSelf:  "No puedes suplantarte a ti mismo",
+		apperror.CodeCannotImpersonateAdmin: "Los administradores no pueden ser suplantados",
+		apperror.CodeNotImpersonating:       "No hay ninguna suplantación en curso",
+		apperror.CodeNotFound:               "No encontrado",
+		apperror.CodeUserNotFound:           "Usuario no encontrado",
+		apperror.CodeConflict:               "Conflicto",
+		apperror.CodeUserExists:             "El correo o el nombre de usuario ya están en uso",
+		apperror.CodeVersionConflict:        "Otra persona modificó el recurso, vuelve a cargarlo e inténtalo de nuevo",
+		apperror.CodeSetupCompleted:         "La configuración ya se ha completado",
+		apperror.CodePreconditionFailed:     "If-Match no coincide con la versión actual, vuelve a cargarla e inténtalo de nuevo",
+		apperror.CodePreconditionRequired:   "La cabecera If-Match es obligatoria",
+		apperror.CodeInvalidIdempotencyKey:  "Idempotency-Key debe tener entre 1 y 255 caracteres",
+		apperror.CodeIdempotencyInFlight:    "Todavía se está procesando una solicitud con esta Idempotency-Key",
+		apperror.CodeIdempotencyKeyReused:   "Esta Idempotency-Key se usó para otra solicitud",
+		apperror.CodeOrganizationRequired:   "Se requiere una organización: envía X-Organization o usa el subdominio de la organización",
+		apperror.CodeOrganizationNotFound:   "Organización no encontrada",
+		apperror.CodeOrganizationExists:     "El identificador de la organización ya está en uso",
+		apperror.CodeNotMember:              "No eres miembro de esta organización",
+		apperror.CodeRateLimited:            "Demasiadas solicitudes",
+		apperror.CodeRequestCanceled:        "Solicitud cancelada",
+		apperror.CodeInternal:               "Error interno del servidor",
+		apperror.CodeTimeout:                "La solicitud ha tardado demasiado, inténtalo de nuevo",
+	},
+}
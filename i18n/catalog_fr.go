@@ -0,0 +1,63 @@
+package i18n
+
+import (
+	"github.com/go-playground/locales/fr"
+	"github.com/kenztech/go-api-starter/apperror"
+)
+
+var french = catalog{
+	locale: fr.New(),
+	rules: map[string]string{
+		"required":         "{0} est obligatoire",
+		"required_without": "{0} est obligatoire si {1} est absent",
+		"email":            "{0} doit être une adresse e-mail valide",
+		"min":              "{0} doit contenir au moins {1} caractères",
+		"gte":              "{0} doit être supérieur ou égal à {1}",
+		"alphanum":         "{0} ne doit contenir que des lettres et des chiffres",
+		"oneof":            "{0} doit être l'une des valeurs suivantes : {1}",
+		"max_bytes":        "{0} ne doit pas dépasser {1} octets",
+		"uppercase":        "{0} doit contenir une lettre majuscule",
+		"lowercase":        "{0} doit contenir une lettre minuscule",
+		"digit":            "{0} doit contenir un chiffre",
+		"symbol":           "{0} doit contenir un symbole",
+		"no_identifiers":   "{0} ne doit pas contenir votre e-mail ou votre nom d'utilisateur",
+		"not_breached":     "{0} figure dans une fuite de données, veuillez en choisir un autre",
+		"not_reused":       "{0} ne doit pas correspondre à un mot de passe utilisé récemment",
+		"slug":             "{0} ne doit contenir que des minuscules, des chiffres et des tirets, comme un sous-domaine",
+	},
+	codes: map[apperror.Code]string{
+		apperror.CodeInvalidRequest:         "Corps de requête invalide",
+		apperror.CodeValidationFailed:       "La validation a échoué",
+		apperror.CodeInvalidID:              "Identifiant invalide",
+		apperror.CodeUnauthorized:           "Non autorisé",
+		apperror.CodeInvalidCredentials:     "Identifiants invalides",
+		apperror.CodeInvalidResetToken:      "Jeton de réinitialisation invalide ou expiré",
+		apperror.CodeInvalidSetupToken:      "Jeton d'installation invalide ou expiré",
+		apperror.CodeForbidden:              "Accès refusé",
+		apperror.CodeAccountInactive:        "Le compte n'est pas actif",
+		apperror.CodePasswordChangeRequired: "Un changement de mot de passe est requis",
+		apperror.CodeImpersonationForbidden: "Action impossible pendant une usurpation d'identité",
+		apperror.CodeCannotImpersonateSelf:  "Vous ne pouvez pas usurper votre propre identité",
+		apperror.CodeCannotImpersonateAdmin: "L'identité d'un administrateur ne peut pas être usurpée",
+		apperror.CodeNotImpersonating:       "Aucune usurpation d'identité en cours",
+		apperror.CodeNotFound:               "Introuvable",
+		apperror.CodeUserNotFound:           "Utilisateur introuvable",
+		apperror.CodeConflict:               "Conflit",
+		apperror.CodeUserExists:             "E-mail ou nom d'utilisateur déjà utilisé",
+		apperror.CodeVersionConflict:        "La ressource a été modifiée par quelqu'un d'autre, rechargez-la et réessayez",
+		apperror.CodeSetupCompleted:         "L'installation a déjà été effectuée",
+		apperror.CodePreconditionFailed:     "If-Match ne correspond pas à la version actuelle, rechargez-la et réessayez",
+		apperror.CodePreconditionRequired:   "L'en-tête If-Match est obligatoire",
+		apperror.CodeInvalidIdempotencyKey:  "Idempotency-Key doit contenir de 1 à 255 caractères",
+		apperror.CodeIdempotencyInFlight:    "Une requête avec cette Idempotency-Key est encore en cours de traitement",
+		apperror.CodeIdempotencyKeyReused:   "Cette Idempotency-Key a été utilisée pour une autre requête",
+		apperror.CodeOrganizationRequired:   "Une organisation est requise : envoyez X-Organization ou utilisez le sous-domaine de l'organisation",
+		apperror.CodeOrganizationNotFound:   "Organisation introuvable",
+		apperror.CodeOrganizationExists:     "Identifiant d'organisation déjà utilisé",
+		apperror.CodeNotMember:              "Vous n'êtes pas membre de cette organisation",
+		apperror.CodeRateLimited:            "Trop de requêtes",
+		apperror.CodeRequestCanceled:        "Requête annulée",
+		apperror.CodeInternal:               "Erreur interne du serveur",
+		apperror.CodeTimeout:                "La requête a pris trop de temps, veuillez réessayer",
+	},
+}
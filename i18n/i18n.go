@@ -0,0 +1,109 @@
+package i18n
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-playground/locales"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/kenztech/go-api-starter/apperror"
+	"github.com/kenztech/go-api-starter/models/api"
+	"golang.org/x/text/language"
+)
+
+// catalog holds the messages of one language. Rule messages take the field
+// as {0} and the rule parameter as {1}, in that order.
+type catalog struct {
+	locale locales.Translator
+	rules  map[string]string
+	codes  map[apperror.Code]string
+}
+
+var catalogs = []catalog{english, french, spanish, german}
+
+var universal *ut.UniversalTranslator
+
+func init() {
+	universal = ut.New(english.locale, english.locale)
+	for _, c := range catalogs {
+		if err := universal.AddTranslator(c.locale, true); err != nil {
+			panic(err)
+		}
+		trans, _ := universal.GetTranslator(c.locale.Locale())
+		for rule, text := range c.rules {
+			if err := trans.Add(ruleKey(rule), text, true); err != nil {
+				panic(fmt.Sprintf("i18n: rule %q in %s: %v", rule, c.locale.Locale(), err))
+			}
+		}
+		for code, text := range c.codes {
+			if err := trans.Add(codeKey(code), text, true); err != nil {
+				panic(fmt.Sprintf("i18n: code %q in %s: %v", code, c.locale.Locale(), err))
+			}
+		}
+	}
+}
+
+func ruleKey(rule string) string {
+	return "rule." + rule
+}
+
+func codeKey(code apperror.Code) string {
+	return "code." + string(code)
+}
+
+// Default is the English translator, also used when no requested language
+// is supported
+func Default() ut.Translator {
+	return universal.GetFallback()
+}
+
+// FromRequest returns the translator for the preferred supported language of
+// the request's Accept-Language header
+func FromRequest(r *http.Request) ut.Translator {
+	tags, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	if err != nil || len(tags) == 0 {
+		return Default()
+	}
+
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		base, _ := tag.Base()
+		names = append(names, base.String())
+	}
+	trans, _ := universal.FindTranslator(names...)
+	return trans
+}
+
+// RuleMessage returns the message for a failed validation rule, or "" when
+// the language has none
+func RuleMessage(trans ut.Translator, rule, field, param string) string {
+	message, err := trans.T(ruleKey(rule), field, param)
+	if err != nil {
+		return ""
+	}
+	return message
+}
+
+// Translate returns the error with its message and field errors in the
+// translator's language. English is the language errors are written in, so
+// English errors are returned unchanged, keeping their specific messages.
+func Translate(trans ut.Translator, e *apperror.AppError) *apperror.AppError {
+	if trans.Locale() == english.locale.Locale() {
+		return e
+	}
+
+	c := *e
+	if message, err := trans.T(codeKey(e.Code)); err == nil {
+		c.Message = message
+	}
+	if len(e.Fields) > 0 {
+		c.Fields = make([]api.FieldError, len(e.Fields))
+		for i, f := range e.Fields {
+			if message := RuleMessage(trans, f.Rule, f.Field, f.Param); message != "" {
+				f.Message = message
+			}
+			c.Fields[i] = f
+		}
+	}
+	return &c
+}
@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/kenztech/go-api-starter/config"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys whose values never reach the logs. Keys
+// ending in _password, _token, _secret or _otp are redacted as well.
+var sensitiveKeys = map[string]bool{
+	"password":      true,
+	"token":         true,
+	"otp":           true,
+	"secret":        true,
+	"authorization": true,
+	"cookie":        true,
+	"set-cookie":    true,
+}
+
+// New builds the application logger from the configuration
+func New(cfg config.LogConfig) *slog.Logger {
+	return newLogger(os.Stderr, cfg)
+}
+
+func newLogger(w io.Writer, cfg config.LogConfig) *slog.Logger {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		level = slog.LevelInfo
+	}
+
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redact}
+	if cfg.Format == "text" {
+		return slog.New(slog.NewTextHandler(w, opts))
+	}
+	return slog.New(slog.NewJSONHandler(w, opts))
+}
+
+// redact hides the value of sensitive attributes, including nested ones
+func redact(groups []string, a slog.Attr) slog.Attr {
+	if IsSensitive(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+	return a
+}
+
+// IsSensitive reports whether values under this key must not be logged
+func IsSensitive(key string) bool {
+	key = strings.ToLower(key)
+	if sensitiveKeys[key] {
+		return true
+	}
+	for _, suffix := range []string{"_password", "_token", "_secret", "_otp"} {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey struct{}
+
+// requestLogger is shared by every layer handling a request so attributes
+// added deep in the chain (such as the user) show up in the access log.
+type requestLogger struct {
+	mu     sync.Mutex
+	logger *slog.Logger
+}
+
+// NewContext stores a request-scoped logger in the context
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, &requestLogger{logger: logger})
+}
+
+// FromContext returns the request-scoped logger, or the default logger
+// outside of a request.
+func FromContext(ctx context.Context) *slog.Logger {
+	if rl, ok := ctx.Value(contextKey{}).(*requestLogger); ok {
+		rl.mu.Lock()
+		defer rl.mu.Unlock()
+		return rl.logger
+	}
+	return slog.Default()
+}
+
+// AddAttrs adds attributes to the request-scoped logger for the rest of the request
+func AddAttrs(ctx context.Context, attrs ...any) {
+	if rl, ok := ctx.Value(contextKey{}).(*requestLogger); ok {
+		rl.mu.Lock()
+		defer rl.mu.Unlock()
+		rl.logger = rl.logger.With(attrs...)
+	}
+}
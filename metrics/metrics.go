@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "api"
+
+// Registry holds every collector exposed by Handler. A dedicated registry
+// keeps metrics registered by dependencies out of the endpoint.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route pattern and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route pattern and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	Logins = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Login attempts by result and failure reason.",
+	}, []string{"result", "reason"})
+
+	TokensIssued = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tokens_issued_total",
+		Help:      "Tokens issued by type.",
+	}, []string{"type"})
+
+	TokensRevoked = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_revocations_total",
+		Help:      "Revocations of all tokens of a user.",
+	})
+
+	EmailsSent = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "emails_sent_total",
+		Help:      "Emails handed to the SMTP server by result.",
+	}, []string{"result"})
+
+	MongoDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mongo_command_duration_seconds",
+		Help:      "MongoDB command latency by command name and result.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"command", "result"})
+)
+
+// Login results and failure reasons
+const (
+	LoginSuccess        = "success"
+	LoginFailure        = "failure"
+	ReasonInvalidInput  = "invalid_request"
+	ReasonUnknownUser   = "unknown_user"
+	ReasonBadPassword   = "bad_password"
+	ReasonInactive      = "inactive"
+	ReasonInternalError = "internal_error"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// LoginSucceeded counts a successful login
+func LoginSucceeded() {
+	Logins.WithLabelValues(LoginSuccess, "").Inc()
+}
+
+// LoginFailed counts a failed login with its reason
+func LoginFailed(reason string) {
+	Logins.WithLabelValues(LoginFailure, reason).Inc()
+}
+
+// EmailResult counts a sent email, or a failure when err is not nil
+func EmailResult(err error) {
+	if err != nil {
+		EmailsSent.WithLabelValues("failure").Inc()
+		return
+	}
+	EmailsSent.WithLabelValues("success").Inc()
+}
@@ -0,0 +1,20 @@
+package metrics
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/event"
+)
+
+// CommandMonitor records the latency of every MongoDB command sent by the
+// driver, labeled by command name (find, insert, update, ...).
+func CommandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			MongoDuration.WithLabelValues(e.CommandName, "success").Observe(e.Duration.Seconds())
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			MongoDuration.WithLabelValues(e.CommandName, "failure").Observe(e.Duration.Seconds())
+		},
+	}
+}
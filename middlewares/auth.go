@@ -1,8 +1,15 @@
 package middlewares
 
 import (
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
+	"time"
 
+	"github.com/kenztech/go-api-starter/apperror"
+	"github.com/kenztech/go-api-starter/logging"
+	"github.com/kenztech/go-api-starter/models"
 	"github.com/kenztech/go-api-starter/utils"
 )
 
@@ -10,34 +17,144 @@ type contextKey string
 
 const (
 	UserContextKey contextKey = "user"
+
+	// ImpersonatedByHeader marks responses served to an impersonation session.
+	ImpersonatedByHeader = "X-Impersonated-By"
 )
 
-func Authenticate(next http.Handler) http.Handler {
+var errImpersonating = apperror.New(http.StatusForbidden, apperror.CodeImpersonationForbidden, "Action not allowed while impersonating")
+
+// errImpersonatorRevoked rejects impersonation tokens of admins who lost
+// their rights, or their sessions, since issuing them
+var errImpersonatorRevoked = errors.New("impersonator is no longer an active admin")
+
+// Authenticator verifies the session token and checks it against the user's
+// current state, so disabled accounts and revoked tokens are rejected.
+type Authenticator struct {
+	users  models.UserRepository
+	tokens *utils.TokenManager
+}
+
+func NewAuthenticator(users models.UserRepository, tokens *utils.TokenManager) *Authenticator {
+	return &Authenticator{users, tokens}
+}
+
+func (a *Authenticator) Authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		cookie, err := r.Cookie("token")
 		if err != nil || cookie.Value == "" {
-			utils.SendError(w, "Unauthorized", http.StatusUnauthorized)
+			utils.WriteError(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		userData, err := a.tokens.VerifyRefreshToken(cookie.Value)
+		if err != nil {
+			utils.WriteError(w, r, apperror.ErrUnauthorized)
 			return
 		}
 
-		userEmail, userRole, err := utils.VerifyRefreshToken(cookie.Value)
+		lookupCtx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		user, err := a.findUser(lookupCtx, userData)
+		if err == nil && userData.IsImpersonated() {
+			err = a.checkImpersonator(lookupCtx, userData)
+		}
+		cancel()
 		if err != nil {
-			utils.SendError(w, "Unauthorized", http.StatusUnauthorized)
+			if !errors.Is(err, models.ErrUserNotFound) && !errors.Is(err, errImpersonatorRevoked) {
+				logging.FromContext(r.Context()).Error("Error loading user for authentication", "error", err)
+			}
+			utils.WriteError(w, r, apperror.ErrUnauthorized)
 			return
 		}
+		if user.Status != "active" || isRevoked(userData, user.TokensRevokedAt) {
+			utils.WriteError(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		// The stored user is authoritative, including for an email changed
+		// since the token was issued.
+		userData.ID = user.ID.Hex()
+		userData.Email = user.Email
+
+		logging.AddAttrs(r.Context(), slog.String("user_id", userData.ID), slog.String("user_email", userData.Email))
+		if userData.IsImpersonated() {
+			w.Header().Set(ImpersonatedByHeader, userData.ImpersonatorEmail)
+			logging.AddAttrs(r.Context(), slog.String("impersonator_email", userData.ImpersonatorEmail))
+		}
 
-		ctx := utils.SetUserDataInContext(r.Context(), userEmail, userRole)
+		ctx := utils.SetUserDataInContext(r.Context(), userData)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// findUser loads the token's user by ID, or by email for tokens issued
+// before they carried the ID
+func (a *Authenticator) findUser(ctx context.Context, userData utils.UserData) (*models.User, error) {
+	if userData.ID == "" {
+		return a.users.FindByEmail(ctx, userData.Email)
+	}
+
+	id, err := utils.ParseID(userData.ID)
+	if err != nil {
+		return nil, models.ErrUserNotFound
+	}
+	return a.users.FindByID(ctx, id)
+}
+
+// checkImpersonator makes sure the admin behind an impersonation token
+// still is an active admin whose tokens were not revoked since it was
+// issued
+func (a *Authenticator) checkImpersonator(ctx context.Context, userData utils.UserData) error {
+	actor, err := a.users.FindByEmail(ctx, userData.ImpersonatorEmail)
+	if err != nil {
+		return err
+	}
+	if actor.Role != "admin" || actor.Status != "active" || isRevoked(userData, actor.TokensRevokedAt) {
+		return errImpersonatorRevoked
+	}
+	return nil
+}
+
+// isRevoked reports whether the token predates a revocation
+func isRevoked(userData utils.UserData, revokedAt time.Time) bool {
+	if revokedAt.IsZero() {
+		return false
+	}
+	return userData.IssuedBefore(revokedAt)
+}
+
 // AdminOnly is a middleware that ensures the user has the 'admin' role
 func AdminOnly(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		userData, ok := utils.GetUserDataFromContext(r.Context())
 		if !ok || userData.Role != "admin" {
-			utils.SendError(w, "Forbidden", http.StatusForbidden)
+			utils.WriteError(w, r, apperror.ErrForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// NoImpersonation blocks sensitive actions (password change, 2FA, ...) while
+// an admin is impersonating the user.
+func NoImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userData, ok := utils.GetUserDataFromContext(r.Context())
+		if !ok || userData.IsImpersonated() {
+			utils.WriteError(w, r, errImpersonating)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// PasswordChanged blocks users who still have to replace a generated password
+func PasswordChanged(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userData, ok := utils.GetUserDataFromContext(r.Context())
+		if !ok || userData.MustChangePassword {
+			utils.WriteError(w, r, apperror.ErrPasswordChangeRequired)
 			return
 		}
 		next.ServeHTTP(w, r)
@@ -0,0 +1,182 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kenztech/go-api-starter/apperror"
+	"github.com/kenztech/go-api-starter/logging"
+	"github.com/kenztech/go-api-starter/models"
+	"github.com/kenztech/go-api-starter/tenant"
+	"github.com/kenztech/go-api-starter/utils"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	maxIdempotentRequestBytes = 1 << 20
+)
+
+// replayedHeaders are the response headers stored and replayed with the body
+var replayedHeaders = []string{"Content-Type", "Content-Language", "Location", "ETag"}
+
+// Idempotency makes POST requests safe to retry. The first response to a
+// request carrying an Idempotency-Key is stored and replayed to retries with
+// the same key and body. Retries while the first request is in flight get a
+// 409, and reusing a key for a different request a 422. Responses with a 5xx
+// status are not stored, so the request can be retried for real.
+type Idempotency struct {
+	store models.IdempotencyStore
+	ttl   time.Duration
+}
+
+func NewIdempotency(store models.IdempotencyStore, ttl time.Duration) *Idempotency {
+	return &Idempotency{store, ttl}
+}
+
+func (i *Idempotency) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			utils.WriteError(w, r, apperror.ErrInvalidIdempotencyKey)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentRequestBytes))
+		if err != nil {
+			utils.WriteError(w, r, apperror.ErrInvalidBody.Wrap(err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		record, err := i.store.Begin(r.Context(), scopedKey(r, key), fingerprint(r, body), i.ttl)
+		if errors.Is(err, models.ErrIdempotencyKeyTaken) {
+			replay(w, r, record, fingerprint(r, body))
+			return
+		}
+		if err != nil {
+			utils.WriteError(w, r, err)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w}
+		completed := false
+		defer func() {
+			if !completed {
+				// Failed or panicked: free the key so the client can retry.
+				i.release(r.Context(), record)
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		if rec.status >= http.StatusInternalServerError {
+			return
+		}
+
+		record.Status = rec.status
+		record.Header = make(map[string]string)
+		for _, name := range replayedHeaders {
+			if value := w.Header().Get(name); value != "" {
+				record.Header[name] = value
+			}
+		}
+		record.Body = rec.body.Bytes()
+
+		// The response is already sent: store it even if the client went away.
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+		defer cancel()
+		if err := i.store.Complete(ctx, record); err != nil {
+			logging.FromContext(r.Context()).Error("Error storing idempotent response", "error", err)
+			return
+		}
+		completed = true
+	})
+}
+
+func (i *Idempotency) release(ctx context.Context, record *models.IdempotencyRecord) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := i.store.Release(ctx, record); err != nil {
+		logging.FromContext(ctx).Error("Error releasing idempotency key", "error", err)
+	}
+}
+
+// replay answers a retry from the stored record of the first request
+func replay(w http.ResponseWriter, r *http.Request, record *models.IdempotencyRecord, fingerprint string) {
+	if record.Fingerprint != fingerprint {
+		utils.WriteError(w, r, apperror.ErrIdempotencyKeyReused)
+		return
+	}
+	if record.InFlight() {
+		w.Header().Set("Retry-After", "1")
+		utils.WriteError(w, r, apperror.ErrIdempotencyInFlight)
+		return
+	}
+
+	for name, value := range record.Header {
+		w.Header().Set(name, value)
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(record.Status)
+	w.Write(record.Body)
+}
+
+// scopedKey keeps the keys of different users, organizations and endpoints
+// apart. Anonymous requests share a scope, since a retry may come from
+// another address.
+func scopedKey(r *http.Request, key string) string {
+	user := "anonymous"
+	if data, ok := utils.GetUserDataFromContext(r.Context()); ok {
+		user = data.ID
+	}
+	if t, ok := tenant.FromContext(r.Context()); ok {
+		user += "@" + t.OrgID.Hex()
+	}
+	return user + " " + r.URL.Path + " " + key
+}
+
+// fingerprint identifies the request a key was first used for
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+" "+strconv.Itoa(len(body))+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder passes the response through while keeping a copy
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
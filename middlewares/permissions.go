@@ -0,0 +1,38 @@
+package middlewares
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/kenztech/go-api-starter/apperror"
+	"github.com/kenztech/go-api-starter/tenant"
+	"github.com/kenztech/go-api-starter/utils"
+)
+
+// Permissions grants named permissions, such as "widgets.delete", to roles.
+// Admins hold every permission. Behind Tenancy.Resolve, the user's role in
+// the organization is checked instead of their own role.
+type Permissions map[string][]string
+
+// Allows reports whether the role holds the permission
+func (p Permissions) Allows(role, permission string) bool {
+	return role == "admin" || slices.Contains(p[permission], role)
+}
+
+// Require is a middleware rejecting users whose role lacks the permission
+func (p Permissions) Require(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userData, ok := utils.GetUserDataFromContext(r.Context())
+			role := userData.Role
+			if t, found := tenant.FromContext(r.Context()); found {
+				role = t.Role
+			}
+			if !ok || !p.Allows(role, permission) {
+				utils.WriteError(w, r, apperror.ErrForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package middlewares
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kenztech/go-api-starter/apperror"
+	"github.com/kenztech/go-api-starter/config"
+	"github.com/kenztech/go-api-starter/utils"
+)
+
+// RateLimiter allows a fixed number of requests per client IP and window
+type RateLimiter struct {
+	enabled  bool
+	requests int
+	window   time.Duration
+
+	mu          sync.Mutex
+	windowStart time.Time
+	counts      map[string]int
+}
+
+func NewRateLimiter(cfg config.RateLimitConfig) *RateLimiter {
+	return &RateLimiter{
+		enabled:  cfg.Enabled,
+		requests: cfg.Requests,
+		window:   cfg.Window,
+		counts:   make(map[string]int),
+	}
+}
+
+func (l *RateLimiter) Limit(next http.Handler) http.Handler {
+	if !l.enabled {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed, retryAfter := l.allow(clientIP(r))
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			utils.WriteError(w, r, apperror.ErrRateLimited)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *RateLimiter) allow(ip string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.windowStart) >= l.window {
+		l.windowStart = now
+		l.counts = make(map[string]int)
+	}
+
+	if l.counts[ip] >= l.requests {
+		return false, l.window - now.Sub(l.windowStart)
+	}
+	l.counts[ip]++
+	return true, 0
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
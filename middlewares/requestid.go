@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/kenztech/go-api-starter/requestid"
+)
+
+// RequestID gives every request an ID, echoed in the X-Request-ID response
+// header. An incoming X-Request-ID is reused only when trusted, i.e. when a
+// proxy we control sets it.
+func RequestID(trustIncoming bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(requestid.Header)
+			if !trustIncoming || !requestid.Valid(id) {
+				id = requestid.New()
+			}
+
+			w.Header().Set(requestid.Header, id)
+			ctx := requestid.NewContext(r.Context(), id)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
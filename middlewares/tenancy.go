@@ -0,0 +1,99 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kenztech/go-api-starter/apperror"
+	"github.com/kenztech/go-api-starter/logging"
+	"github.com/kenztech/go-api-starter/models"
+	"github.com/kenztech/go-api-starter/tenant"
+	"github.com/kenztech/go-api-starter/utils"
+)
+
+// OrganizationHeader selects the organization of a request by ID or slug
+const OrganizationHeader = "X-Organization"
+
+// Tenancy resolves the organization a request acts for
+type Tenancy struct {
+	orgs   models.OrganizationRepository
+	domain string
+}
+
+// NewTenancy resolves organizations from orgs. Subdomains of domain, when
+// set, name organizations by their slug.
+func NewTenancy(orgs models.OrganizationRepository, domain string) *Tenancy {
+	return &Tenancy{orgs, strings.ToLower(domain)}
+}
+
+// Resolve puts the request's tenant in its context. The organization is
+// taken from the X-Organization header, else from the subdomain, else from
+// the session picked at login. Users who aren't members are rejected, so
+// everything behind Resolve can trust the tenant. It must run after
+// Authenticate.
+func (t *Tenancy) Resolve(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userData, ok := utils.GetUserDataFromContext(r.Context())
+		if !ok {
+			utils.WriteError(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		ref := t.organization(r, userData)
+		if ref == "" {
+			utils.WriteError(w, r, apperror.ErrOrganizationRequired)
+			return
+		}
+
+		userID, err := utils.ParseID(userData.ID)
+		if err != nil {
+			utils.WriteError(w, r, apperror.ErrUnauthorized)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		org, role, err := models.FindMemberOrganization(ctx, t.orgs, ref, userID, userData.Role)
+		cancel()
+		switch {
+		case errors.Is(err, models.ErrNotMember):
+			utils.WriteError(w, r, apperror.ErrNotMember)
+			return
+		case errors.Is(err, models.ErrOrganizationNotFound):
+			utils.WriteError(w, r, apperror.ErrOrganizationNotFound)
+			return
+		case err != nil:
+			utils.WriteError(w, r, err)
+			return
+		}
+
+		logging.AddAttrs(r.Context(), slog.String("org_id", org.ID.Hex()))
+
+		ctx = tenant.NewContext(r.Context(), tenant.Tenant{OrgID: org.ID, Role: role})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// organization returns the ID or slug of the requested organization, or ""
+func (t *Tenancy) organization(r *http.Request, userData utils.UserData) string {
+	if ref := r.Header.Get(OrganizationHeader); ref != "" {
+		return ref
+	}
+
+	if t.domain != "" {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		slug, ok := strings.CutSuffix(strings.ToLower(host), "."+t.domain)
+		if ok && slug != "" && !strings.Contains(slug, ".") {
+			return slug
+		}
+	}
+
+	return userData.OrgID
+}
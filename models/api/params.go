@@ -1,6 +1,9 @@
 package api
 
-import "time"
+import (
+	"strconv"
+	"time"
+)
 
 type SuccessResponse struct {
 	Success bool        `json:"success"`
@@ -9,24 +12,93 @@ type SuccessResponse struct {
 }
 
 type ErrorResponse struct {
-	Success bool   `json:"success"`
+	Success   bool         `json:"success"`
+	Message   string       `json:"message"`
+	Code      string       `json:"code"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// FieldError describes one failed validation rule
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
 	Message string `json:"message"`
 }
 
+// Problem is the RFC 7807 representation of an error, sent to clients that
+// accept application/problem+json
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
 type LoginRequest struct {
-	Email    string `json:"email" validate:"omitempty,email"`
-	Username string `json:"username" validate:"omitempty"`
+	// Email or Username identifies the user
+	Email    string `json:"email" validate:"required_without=Username,omitempty,email"`
+	Username string `json:"username" validate:"required_without=Email"`
 	Password string `json:"password" validate:"required,min=6"`
+	// Organization is the ID or slug of the organization the session acts
+	// for by default
+	Organization string `json:"organization,omitempty"`
+}
+
+type RegisterRequest struct {
+	Name     string `json:"name,omitempty"`
+	Username string `json:"username" validate:"required,alphanum,min=3"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type SetupRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Name     string `json:"name,omitempty"`
+	Username string `json:"username" validate:"required,alphanum,min=3"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
 }
 
 type UserRequest struct {
 	Name     string `json:"name,omitempty"`
-	Password string `json:"password" validate:"required,min=6"`
+	Username string `json:"username,omitempty" validate:"omitempty,alphanum,min=3"`
+	Password string `json:"password" validate:"required"`
 	Email    string `json:"email" validate:"required,email"`
 	Role     string `json:"role" validate:"required,oneof=admin merchant operator"`
 	Status   string `json:"status" validate:"required,oneof=active inactive banned"`
 }
 
+// UpdateUserRequest changes the fields that are set and keeps the others.
+// Version, when set, must be the version the change is based on.
+type UpdateUserRequest struct {
+	Version  *int64  `json:"version,omitempty"`
+	Name     *string `json:"name,omitempty"`
+	Username *string `json:"username,omitempty" validate:"omitempty,alphanum,min=3"`
+	Email    *string `json:"email,omitempty" validate:"omitempty,email"`
+	Role     *string `json:"role,omitempty" validate:"omitempty,oneof=admin merchant operator"`
+	Status   *string `json:"status,omitempty" validate:"omitempty,oneof=active inactive banned"`
+}
+
 type Pagination struct {
 	Page       int   `json:"page"`
 	Size       int   `json:"size"`
@@ -45,12 +117,90 @@ type UserResponse struct {
 	User    UserData `json:"user"`
 }
 
+// ETag identifies the user's current version
+func (r UserResponse) ETag() string {
+	return r.User.ETag()
+}
+
 type UserData struct {
-	ID        uint      `json:"id"`
+	ID                 string     `json:"id"`
+	Name               string     `json:"name,omitempty"`
+	Username           string     `json:"username"`
+	Email              string     `json:"email"`
+	Role               string     `json:"role"`
+	Status             string     `json:"status"`
+	MustChangePassword bool       `json:"must_change_password,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+	DeletedAt          *time.Time `json:"deleted_at,omitempty"`
+	Version            int64      `json:"version"`
+}
+
+// ETag changes with every update of the user
+func (u UserData) ETag() string {
+	return `"` + u.ID + "-" + strconv.FormatInt(u.Version, 10) + `"`
+}
+
+type OrganizationRequest struct {
+	Name string `json:"name" validate:"required"`
+	Slug string `json:"slug" validate:"required,slug"`
+}
+
+type OrganizationData struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+	// Role is the caller's role in the organization
+	Role      string    `json:"role,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type OrganizationResponse struct {
+	Success      bool             `json:"success"`
+	Organization OrganizationData `json:"organization"`
+}
+
+type OrganizationsResponse struct {
+	Success       bool               `json:"success"`
+	Organizations []OrganizationData `json:"organizations"`
+}
+
+// AddMemberRequest names the new member by email rather than ID
+type AddMemberRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required,oneof=admin merchant operator"`
+}
+
+type MemberRequest struct {
+	Role string `json:"role" validate:"required,oneof=admin merchant operator"`
+}
+
+type MemberData struct {
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name,omitempty"`
 	Username  string    `json:"username"`
 	Email     string    `json:"email"`
 	Role      string    `json:"role"`
-	Status    string    `json:"status"`
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type MemberResponse struct {
+	Success bool       `json:"success"`
+	Member  MemberData `json:"member"`
+}
+
+type MembersResponse struct {
+	Success bool         `json:"success"`
+	Members []MemberData `json:"members"`
+}
+
+type HealthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
 }
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kenztech/go-api-starter/requestid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const (
+	AuditImpersonationStart = "impersonation.start"
+	AuditImpersonationStop  = "impersonation.stop"
+)
+
+type AuditLog struct {
+	ID          bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	Action      string        `bson:"action" json:"action"`
+	ActorEmail  string        `bson:"actor_email" json:"actor_email"`
+	TargetEmail string        `bson:"target_email" json:"target_email"`
+	IPAddress   string        `bson:"ip_address" json:"ip_address"`
+	UserAgent   string        `bson:"user_agent" json:"user_agent"`
+	RequestID   string        `bson:"request_id,omitempty" json:"request_id,omitempty"`
+	CreatedAt   time.Time     `bson:"created_at" json:"created_at"`
+}
+
+// AuditRecorder stores audit entries
+type AuditRecorder interface {
+	Record(ctx context.Context, entry AuditLog) error
+}
+
+type mongoAuditRecorder struct {
+	collection *mongo.Collection
+}
+
+// NewMongoAuditRecorder stores audit entries in the audit_logs collection
+func NewMongoAuditRecorder(db *mongo.Database) AuditRecorder {
+	return &mongoAuditRecorder{db.Collection("audit_logs")}
+}
+
+func (a *mongoAuditRecorder) Record(ctx context.Context, entry AuditLog) error {
+	_, err := a.collection.InsertOne(ctx, newAuditEntry(ctx, entry))
+	return err
+}
+
+// MemoryAuditRecorder keeps audit entries in memory, for tests
+type MemoryAuditRecorder struct {
+	mu      sync.Mutex
+	entries []AuditLog
+}
+
+func (a *MemoryAuditRecorder) Record(ctx context.Context, entry AuditLog) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.entries = append(a.entries, newAuditEntry(ctx, entry))
+	return nil
+}
+
+// Entries returns the recorded entries, oldest first
+func (a *MemoryAuditRecorder) Entries() []AuditLog {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]AuditLog(nil), a.entries...)
+}
+
+// newAuditEntry fills in the ID, request ID and time when missing
+func newAuditEntry(ctx context.Context, entry AuditLog) AuditLog {
+	if entry.ID.IsZero() {
+		entry.ID = bson.NewObjectID()
+	}
+	if entry.RequestID == "" {
+		entry.RequestID = requestid.FromContext(ctx)
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	return entry
+}
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// BSON stores datetimes in milliseconds and decodes them in UTC
+var testTime = time.Date(2024, 3, 14, 15, 9, 26, 535_000_000, time.UTC)
+
+// roundTrip marshals v, unmarshals the document into a new value of the same
+// type and fails unless both are deeply equal. It returns the document.
+func roundTrip[T any](t *testing.T, v T) bson.Raw {
+	t.Helper()
+
+	data, err := bson.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal %T: %v", v, err)
+	}
+	var got T
+	if err := bson.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal %T: %v", v, err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Fatalf("%T round trip:\n got  %+v\n want %+v", v, got, v)
+	}
+	return data
+}
+
+func assertKeys(t *testing.T, doc bson.Raw, present []string, absent []string) {
+	t.Helper()
+
+	for _, key := range present {
+		if _, err := doc.LookupErr(key); err != nil {
+			t.Errorf("key %q missing from %s", key, doc)
+		}
+	}
+	for _, key := range absent {
+		if _, err := doc.LookupErr(key); err == nil {
+			t.Errorf("key %q should be omitted from %s", key, doc)
+		}
+	}
+}
+
+func TestUserCodec(t *testing.T) {
+	active := User{
+		ID:        bson.NewObjectID(),
+		Name:      "Alice",
+		Role:      "merchant",
+		Email:     "alice@example.com",
+		Status:    "active",
+		Username:  "alice",
+		Password:  "$argon2id$hash",
+		CreatedAt: testTime,
+		UpdatedAt: testTime,
+		Version:   1,
+	}
+	doc := roundTrip(t, active)
+	assertKeys(t, doc,
+		[]string{"_id", "name", "role", "email", "status", "username", "password", "created_at", "updated_at", "version"},
+		[]string{"password_history", "must_change_password", "tokens_revoked_at", "deleted_at"})
+
+	deleted := active
+	deleted.PasswordHistory = []string{"$argon2id$old"}
+	deleted.MustChangePassword = true
+	deleted.TokensRevokedAt = testTime.Add(time.Minute)
+	deleted.DeletedAt = testTime.Add(time.Hour)
+	deleted.Version = 4
+	doc = roundTrip(t, deleted)
+	assertKeys(t, doc,
+		[]string{"password_history", "must_change_password", "tokens_revoked_at", "deleted_at"}, nil)
+
+	// A new user has no ID yet so that MongoDB assigns one
+	assertKeys(t, roundTrip(t, User{Email: "bob@example.com"}), nil, []string{"_id"})
+}
+
+func TestAuditLogCodec(t *testing.T) {
+	entry := AuditLog{
+		ID:          bson.NewObjectID(),
+		Action:      "user.delete",
+		ActorEmail:  "admin@example.com",
+		TargetEmail: "alice@example.com",
+		IPAddress:   "203.0.113.7",
+		UserAgent:   "curl/8.0",
+		RequestID:   "req-1",
+		CreatedAt:   testTime,
+	}
+	roundTrip(t, entry)
+
+	entry.RequestID = ""
+	assertKeys(t, roundTrip(t, entry), []string{"action", "created_at"}, []string{"request_id"})
+}
+
+func TestSetupTokenCodec(t *testing.T) {
+	roundTrip(t, SetupToken{
+		ID:        bson.NewObjectID(),
+		TokenHash: "5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8",
+		ExpiresAt: testTime.Add(time.Hour),
+		CreatedAt: testTime,
+	})
+}
+
+func TestOrganizationCodec(t *testing.T) {
+	org := Organization{
+		ID:        bson.NewObjectID(),
+		Name:      "Acme",
+		Slug:      "acme",
+		CreatedAt: testTime,
+		UpdatedAt: testTime,
+	}
+	roundTrip(t, org)
+
+	doc := roundTrip(t, Membership{
+		ID:        bson.NewObjectID(),
+		OrgID:     org.ID,
+		UserID:    bson.NewObjectID(),
+		Role:      "merchant",
+		CreatedAt: testTime,
+		UpdatedAt: testTime,
+	})
+	assertKeys(t, doc, []string{"org_id", "user_id", "role"}, nil)
+}
+
+func TestIdempotencyRecordCodec(t *testing.T) {
+	pending := IdempotencyRecord{
+		ID:          "user:key-1",
+		Fingerprint: "fingerprint",
+		CreatedAt:   testTime,
+		ExpiresAt:   testTime.Add(24 * time.Hour),
+	}
+	assertKeys(t, roundTrip(t, pending), []string{"_id", "status", "expires_at"}, []string{"header", "body"})
+
+	completed := pending
+	completed.Status = 201
+	completed.Header = map[string]string{"Content-Type": "application/json"}
+	completed.Body = []byte(`{"success":true}`)
+	assertKeys(t, roundTrip(t, completed), []string{"header", "body"}, nil)
+}
+
+func TestMigrationCodec(t *testing.T) {
+	roundTrip(t, migrationLock{
+		ID:        migrationLockID,
+		Owner:     "host-1234",
+		LockedAt:  testTime,
+		ExpiresAt: testTime.Add(migrationLockTTL),
+	})
+
+	doc := roundTrip(t, appliedMigration{Version: 3, Name: "audit_logs_created_at", AppliedAt: testTime})
+	if version, ok := doc.Lookup("_id").Int32OK(); !ok || version != 3 {
+		t.Errorf("applied migration _id = %v, want the version", doc.Lookup("_id"))
+	}
+}
@@ -2,78 +2,67 @@ package models
 
 import (
 	"context"
-	"log"
-	"time"
+	"log/slog"
 
-	"github.com/kenztech/go-api-starter/utils"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"github.com/kenztech/go-api-starter/config"
+	"go.mongodb.org/mongo-driver/v2/event"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
 var DB *mongo.Database
 
-func ConnectDB() (*mongo.Database, error) {
-	utils.LoadEnv()
-
-	db := utils.GetEnv("MONGO_DB", "test")
-	uri := utils.GetEnv("MONGO_URI", "mongodb://localhost:27017")
+// ConnectDB connects to MongoDB. The monitors observe every command the
+// driver sends, in order.
+func ConnectDB(cfg config.MongoConfig, monitors ...*event.CommandMonitor) (*mongo.Database, error) {
+	opts := options.Client().ApplyURI(cfg.URI)
+	if len(monitors) > 0 {
+		opts.SetMonitor(combineMonitors(monitors))
+	}
 
-	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	client, err := mongo.Connect(opts)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	// Ensure the connection is successful
 	err = client.Ping(context.Background(), nil)
 	if err != nil {
-		log.Fatal("Error pinging MongoDB:", err)
+		slog.Error("Error pinging MongoDB", "error", err)
+		client.Disconnect(context.Background())
 		return nil, err
 	}
 
-	log.Println("Connected to MongoDB successfully!")
-	DB := client.Database(db)
-
-	// Ensure an admin user exists
-	intitAdminUser(DB)
+	slog.Info("Connected to MongoDB successfully!")
+	DB = client.Database(cfg.Database)
 
 	return DB, nil
 }
 
-// Ensure an admin user exists in the database
-func intitAdminUser(db *mongo.Database) {
-	collection := db.Collection("users")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	// Check if an admin user already exists
-	var existingUser User
-	err := collection.FindOne(ctx, bson.M{"role": "admin"}).Decode(&existingUser)
-	if err == mongo.ErrNoDocuments {
-		// Create an admin user if none exists
-		hashedPassword, err := utils.HashPassword("Admin@kenz.io")
-		if err != nil {
-			log.Fatal("Error hashing admin password:", err)
-		}
-
-		admin := User{
-			ID:       primitive.NewObjectID(),
-			Name:     "Admin",
-			Role:     "admin",
-			Username: "admin",
-			Status:   "active",
-			Email:    "admin@kenz.io",
-			Password: string(hashedPassword),
-		}
-
-		_, err = collection.InsertOne(ctx, admin)
-		if err != nil {
-			log.Println("Error creating admin user:", err)
-		} else {
-			log.Println("Admin user created successfully!")
-		}
-	} else if err != nil {
-		log.Println("Error checking for admin user:", err)
+// combineMonitors fans driver events out to several monitors, since the
+// client accepts only one
+func combineMonitors(monitors []*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, m := range monitors {
+				if m.Started != nil {
+					m.Started(ctx, e)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, m := range monitors {
+				if m.Succeeded != nil {
+					m.Succeeded(ctx, e)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, m := range monitors {
+				if m.Failed != nil {
+					m.Failed(ctx, e)
+				}
+			}
+		},
 	}
 }
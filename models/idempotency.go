@@ -0,0 +1,154 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// idempotencyLockTimeout is how long a request may hold its key before it is
+// presumed dead, e.g. after a crash, and another may take the key over
+const idempotencyLockTimeout = time.Minute
+
+var ErrIdempotencyKeyTaken = errors.New("idempotency key already used")
+
+// IdempotencyRecord is the first response to a request made with an
+// Idempotency-Key. Status is 0 while that request is still in flight.
+type IdempotencyRecord struct {
+	ID          string            `bson:"_id"`
+	Fingerprint string            `bson:"fingerprint"`
+	Status      int               `bson:"status"`
+	Header      map[string]string `bson:"header,omitempty"`
+	Body        []byte            `bson:"body,omitempty"`
+	CreatedAt   time.Time         `bson:"created_at"`
+	ExpiresAt   time.Time         `bson:"expires_at"`
+}
+
+// InFlight reports whether the first request is still being processed
+func (r *IdempotencyRecord) InFlight() bool {
+	return r.Status == 0
+}
+
+// IdempotencyStore keeps idempotency records until they expire. Begin claims
+// a key for a new request; when the key is already claimed it returns
+// ErrIdempotencyKeyTaken with the existing record. Complete stores the
+// response, Release gives up the key so the request can be retried. Both
+// leave the key alone once another request has taken it over.
+type IdempotencyStore interface {
+	Begin(ctx context.Context, id, fingerprint string, ttl time.Duration) (*IdempotencyRecord, error)
+	Complete(ctx context.Context, record *IdempotencyRecord) error
+	Release(ctx context.Context, record *IdempotencyRecord) error
+}
+
+type mongoIdempotencyStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoIdempotencyStore stores records in the idempotency_keys collection,
+// where a TTL index removes them once expired
+func NewMongoIdempotencyStore(db *mongo.Database) IdempotencyStore {
+	return &mongoIdempotencyStore{db.Collection("idempotency_keys")}
+}
+
+func (s *mongoIdempotencyStore) Begin(ctx context.Context, id, fingerprint string, ttl time.Duration) (*IdempotencyRecord, error) {
+	record := newIdempotencyRecord(id, fingerprint, ttl)
+
+	// Like the migration lock, the upsert claims a free key and takes over
+	// an expired or abandoned one. A live record matches nothing, so the
+	// insert hits the duplicate _id.
+	filter := bson.M{"_id": id, "$or": []bson.M{
+		{"expires_at": bson.M{"$lt": record.CreatedAt}},
+		{"status": 0, "created_at": bson.M{"$lt": record.CreatedAt.Add(-idempotencyLockTimeout)}},
+	}}
+	_, err := s.collection.ReplaceOne(ctx, filter, record, options.Replace().SetUpsert(true))
+	if err == nil {
+		return record, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, err
+	}
+
+	var existing IdempotencyRecord
+	err = s.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&existing)
+	if err == mongo.ErrNoDocuments {
+		// Released in the meantime: claim it again.
+		return s.Begin(ctx, id, fingerprint, ttl)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &existing, ErrIdempotencyKeyTaken
+}
+
+func (s *mongoIdempotencyStore) Complete(ctx context.Context, record *IdempotencyRecord) error {
+	update := bson.M{"$set": bson.M{"status": record.Status, "header": record.Header, "body": record.Body}}
+	_, err := s.collection.UpdateOne(ctx, bson.M{"_id": record.ID, "created_at": record.CreatedAt}, update)
+	return err
+}
+
+func (s *mongoIdempotencyStore) Release(ctx context.Context, record *IdempotencyRecord) error {
+	_, err := s.collection.DeleteOne(ctx, bson.M{"_id": record.ID, "created_at": record.CreatedAt, "status": 0})
+	return err
+}
+
+// MemoryIdempotencyStore keeps records in memory, for tests and single
+// instance deployments
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]IdempotencyRecord
+}
+
+func (s *MemoryIdempotencyStore) Begin(ctx context.Context, id, fingerprint string, ttl time.Duration) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := newIdempotencyRecord(id, fingerprint, ttl)
+	if existing, ok := s.records[id]; ok {
+		expired := existing.ExpiresAt.Before(record.CreatedAt)
+		abandoned := existing.InFlight() && existing.CreatedAt.Before(record.CreatedAt.Add(-idempotencyLockTimeout))
+		if !expired && !abandoned {
+			return &existing, ErrIdempotencyKeyTaken
+		}
+	}
+
+	if s.records == nil {
+		s.records = make(map[string]IdempotencyRecord)
+	}
+	s.records[id] = *record
+	return record, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(ctx context.Context, record *IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.records[record.ID]; ok && existing.CreatedAt.Equal(record.CreatedAt) {
+		s.records[record.ID] = *record
+	}
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, record *IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.records[record.ID]; ok && existing.CreatedAt.Equal(record.CreatedAt) && existing.InFlight() {
+		delete(s.records, record.ID)
+	}
+	return nil
+}
+
+func newIdempotencyRecord(id, fingerprint string, ttl time.Duration) *IdempotencyRecord {
+	createdAt := now()
+	return &IdempotencyRecord{
+		ID:          id,
+		Fingerprint: fingerprint,
+		CreatedAt:   createdAt,
+		ExpiresAt:   createdAt.Add(ttl),
+	}
+}
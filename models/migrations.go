@@ -0,0 +1,332 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Migration is a versioned, reversible change to the database
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+	Down    func(ctx context.Context, db *mongo.Database) error
+}
+
+const (
+	migrationLockID = "migrations"
+	// migrationLockTTL bounds how long a runner that died while holding the
+	// lock blocks the others
+	migrationLockTTL = 10 * time.Minute
+)
+
+// migrationLock is held by the runner applying migrations, so instances
+// starting together don't run the same migration twice
+type migrationLock struct {
+	ID        string    `bson:"_id"`
+	Owner     string    `bson:"owner"`
+	LockedAt  time.Time `bson:"locked_at"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+type appliedMigration struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "users_unique_email_username",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("users").Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "email", Value: 1}},
+					Options: options.Index().SetName("email_unique").SetUnique(true),
+				},
+				{
+					Keys: bson.D{{Key: "username", Value: 1}},
+					Options: options.Index().SetName("username_unique").SetUnique(true).
+						SetPartialFilterExpression(bson.M{"username": bson.M{"$gt": ""}}),
+				},
+			})
+			return err
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			indexes := db.Collection("users").Indexes()
+			if err := indexes.DropOne(ctx, "email_unique"); err != nil {
+				return err
+			}
+			return indexes.DropOne(ctx, "username_unique")
+		},
+	},
+	{
+		// Emails and usernames differing only by case belong to the same
+		// person. Lookups use the same collation, so they match either case.
+		Version: 2,
+		Name:    "users_case_insensitive_email_username",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			indexes := db.Collection("users").Indexes()
+			_, err := indexes.CreateMany(ctx, []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "email", Value: 1}},
+					Options: options.Index().SetName("email_unique_ci").SetUnique(true).SetCollation(userCollation),
+				},
+				{
+					Keys: bson.D{{Key: "username", Value: 1}},
+					Options: options.Index().SetName("username_unique_ci").SetUnique(true).SetCollation(userCollation).
+						SetPartialFilterExpression(bson.M{"username": bson.M{"$gt": ""}}),
+				},
+			})
+			if err != nil {
+				return err
+			}
+			if err := indexes.DropOne(ctx, "email_unique"); err != nil {
+				return err
+			}
+			return indexes.DropOne(ctx, "username_unique")
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			indexes := db.Collection("users").Indexes()
+			_, err := indexes.CreateMany(ctx, []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "email", Value: 1}},
+					Options: options.Index().SetName("email_unique").SetUnique(true),
+				},
+				{
+					Keys: bson.D{{Key: "username", Value: 1}},
+					Options: options.Index().SetName("username_unique").SetUnique(true).
+						SetPartialFilterExpression(bson.M{"username": bson.M{"$gt": ""}}),
+				},
+			})
+			if err != nil {
+				return err
+			}
+			if err := indexes.DropOne(ctx, "email_unique_ci"); err != nil {
+				return err
+			}
+			return indexes.DropOne(ctx, "username_unique_ci")
+		},
+	},
+	{
+		// MongoDB removes setup tokens once they expire
+		Version: 3,
+		Name:    "setup_tokens_ttl",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("setup_tokens").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "expires_at", Value: 1}},
+				Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+			})
+			return err
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return db.Collection("setup_tokens").Indexes().DropOne(ctx, "expires_at_ttl")
+		},
+	},
+	{
+		// Users created before timestamps and versions get a version and
+		// timestamps taken from their ObjectID, which holds their creation time.
+		Version: 4,
+		Name:    "users_version_timestamps",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("users").UpdateMany(ctx,
+				bson.M{"version": bson.M{"$exists": false}},
+				mongo.Pipeline{{{Key: "$set", Value: bson.M{
+					"version":    1,
+					"created_at": bson.M{"$toDate": "$_id"},
+					"updated_at": bson.M{"$toDate": "$_id"},
+				}}}},
+			)
+			return err
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			// The fields are harmless to older code, and dropping them would
+			// lose the timestamps of users created since.
+			return nil
+		},
+	},
+	{
+		// MongoDB removes stored idempotent responses once they expire
+		Version: 5,
+		Name:    "idempotency_keys_ttl",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("idempotency_keys").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "expires_at", Value: 1}},
+				Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+			})
+			return err
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return db.Collection("idempotency_keys").Indexes().DropOne(ctx, "expires_at_ttl")
+		},
+	},
+	{
+		// Slugs name organizations in subdomains, and a user is a member of
+		// an organization once
+		Version: 6,
+		Name:    "organizations_memberships",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("organizations").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "slug", Value: 1}},
+				Options: options.Index().SetName("slug_unique").SetUnique(true),
+			})
+			if err != nil {
+				return err
+			}
+			_, err = db.Collection("memberships").Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "org_id", Value: 1}, {Key: "user_id", Value: 1}},
+					Options: options.Index().SetName("org_user_unique").SetUnique(true),
+				},
+				{
+					Keys:    bson.D{{Key: "user_id", Value: 1}},
+					Options: options.Index().SetName("user_id_1"),
+				},
+			})
+			return err
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			memberships := db.Collection("memberships").Indexes()
+			if err := memberships.DropOne(ctx, "org_user_unique"); err != nil {
+				return err
+			}
+			if err := memberships.DropOne(ctx, "user_id_1"); err != nil {
+				return err
+			}
+			return db.Collection("organizations").Indexes().DropOne(ctx, "slug_unique")
+		},
+	},
+}
+
+// Migrations returns the registered migrations ordered by version
+func Migrations() []Migration {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return sorted
+}
+
+// MigrateUp applies every pending migration in order and returns the ones
+// applied. It waits for any other runner to finish first.
+func MigrateUp(ctx context.Context, db *mongo.Database) ([]Migration, error) {
+	unlock, err := lockMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range Migrations() {
+		if applied[m.Version] {
+			continue
+		}
+		if err := m.Up(ctx, db); err != nil {
+			return done, fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		record := appliedMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}
+		if _, err := db.Collection("schema_migrations").InsertOne(ctx, record); err != nil {
+			return done, fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// MigrateDown reverts the last steps applied migrations and returns the ones reverted
+func MigrateDown(ctx context.Context, db *mongo.Database, steps int) ([]Migration, error) {
+	unlock, err := lockMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	all := Migrations()
+	var done []Migration
+	for i := len(all) - 1; i >= 0 && len(done) < steps; i-- {
+		m := all[i]
+		if !applied[m.Version] {
+			continue
+		}
+		if err := m.Down(ctx, db); err != nil {
+			return done, fmt.Errorf("reverting migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		if _, err := db.Collection("schema_migrations").DeleteOne(ctx, bson.M{"_id": m.Version}); err != nil {
+			return done, fmt.Errorf("failed to unrecord migration %d: %w", m.Version, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// lockMigrations waits until it holds the migration lock and returns the
+// function releasing it. A lock past its expiry is taken over.
+func lockMigrations(ctx context.Context, db *mongo.Database) (func(), error) {
+	collection := db.Collection("schema_migrations_lock")
+	owner := bson.NewObjectID().Hex()
+
+	for {
+		now := time.Now()
+		lock := migrationLock{ID: migrationLockID, Owner: owner, LockedAt: now, ExpiresAt: now.Add(migrationLockTTL)}
+
+		// The upsert inserts a free lock and replaces an expired one. A lock
+		// still held matches nothing, so the insert hits the duplicate _id.
+		filter := bson.M{"_id": migrationLockID, "expires_at": bson.M{"$lt": now}}
+		_, err := collection.ReplaceOne(ctx, filter, lock, options.Replace().SetUpsert(true))
+		if err == nil {
+			break
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("acquiring migration lock: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.New("timed out waiting for another migration runner")
+		case <-time.After(time.Second):
+		}
+	}
+
+	unlock := func() {
+		// The caller's context may be done already, the lock must go anyway.
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		collection.DeleteOne(ctx, bson.M{"_id": migrationLockID, "owner": owner})
+	}
+	return unlock, nil
+}
+
+func appliedVersions(ctx context.Context, db *mongo.Database) (map[int]bool, error) {
+	cursor, err := db.Collection("schema_migrations").Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	var records []appliedMigration
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]bool, len(records))
+	for _, record := range records {
+		applied[record.Version] = true
+	}
+	return applied, nil
+}
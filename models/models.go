@@ -1,13 +1,26 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
 
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// User is an account. DeletedAt is set once it is soft deleted. Version
+// counts updates: an update only applies to the version it was read at.
 type User struct {
-	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name     string             `bson:"name" json:"name"`
-	Role     string             `bson:"role" json:"role"`
-	Email    string             `bson:"email" json:"email"`
-	Status   string             `bson:"status" json:"status"`
-	Username string             `bson:"username" json:"username"`
-	Password string             `bson:"password" json:"password"`
+	ID                 bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name               string        `bson:"name" json:"name"`
+	Role               string        `bson:"role" json:"role"`
+	Email              string        `bson:"email" json:"email"`
+	Status             string        `bson:"status" json:"status"`
+	Username           string        `bson:"username" json:"username"`
+	Password           string        `bson:"password" json:"password"`
+	PasswordHistory    []string      `bson:"password_history,omitempty" json:"-"`
+	MustChangePassword bool          `bson:"must_change_password,omitempty" json:"must_change_password"`
+	TokensRevokedAt    time.Time     `bson:"tokens_revoked_at,omitempty" json:"-"`
+	CreatedAt          time.Time     `bson:"created_at" json:"created_at"`
+	UpdatedAt          time.Time     `bson:"updated_at" json:"updated_at"`
+	DeletedAt          time.Time     `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	Version            int64         `bson:"version" json:"version"`
 }
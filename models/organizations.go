@@ -0,0 +1,85 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var (
+	ErrOrganizationNotFound = errors.New("organization not found")
+	ErrOrganizationExists   = errors.New("organization slug already in use")
+	ErrNotMember            = errors.New("user is not a member of the organization")
+)
+
+// Organization is a tenant: a merchant company whose data is kept apart
+// from the others'. Slug names it in subdomains and headers.
+type Organization struct {
+	ID        bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string        `bson:"name" json:"name"`
+	Slug      string        `bson:"slug" json:"slug"`
+	CreatedAt time.Time     `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time     `bson:"updated_at" json:"updated_at"`
+}
+
+// Membership gives a user a role in an organization. The role is one of
+// the user roles and applies to the organization's data only.
+type Membership struct {
+	ID        bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrgID     bson.ObjectID `bson:"org_id" json:"org_id"`
+	UserID    bson.ObjectID `bson:"user_id" json:"user_id"`
+	Role      string        `bson:"role" json:"role"`
+	CreatedAt time.Time     `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time     `bson:"updated_at" json:"updated_at"`
+}
+
+// OrganizationRepository stores organizations and their members. Lookups
+// return ErrOrganizationNotFound, or ErrNotMember for memberships, when
+// nothing matches; Create returns ErrOrganizationExists for a taken slug.
+// List returns the organizations with the given IDs, or all of them when
+// ids is nil. SetMember adds the user or changes their role.
+//
+// Only memberships and resource stores are kept apart by organization:
+// users are global and the UserRepository is not scoped.
+type OrganizationRepository interface {
+	FindByID(ctx context.Context, id bson.ObjectID) (*Organization, error)
+	FindBySlug(ctx context.Context, slug string) (*Organization, error)
+	List(ctx context.Context, ids []bson.ObjectID) ([]Organization, error)
+	Create(ctx context.Context, org *Organization) error
+	FindMembership(ctx context.Context, orgID, userID bson.ObjectID) (*Membership, error)
+	Members(ctx context.Context, orgID bson.ObjectID) ([]Membership, error)
+	UserMemberships(ctx context.Context, userID bson.ObjectID) ([]Membership, error)
+	SetMember(ctx context.Context, membership *Membership) error
+	RemoveMember(ctx context.Context, orgID, userID bson.ObjectID) error
+}
+
+// FindMemberOrganization looks an organization up by its hex ID or its
+// slug and returns it with the user's role in it. Admins act as admins of
+// every organization. Other users must be members, and get ErrNotMember for
+// organizations that don't exist so they can't probe for them.
+func FindMemberOrganization(ctx context.Context, orgs OrganizationRepository, ref string, userID bson.ObjectID, role string) (*Organization, string, error) {
+	var org *Organization
+	var err error
+	if id, parseErr := bson.ObjectIDFromHex(ref); parseErr == nil {
+		org, err = orgs.FindByID(ctx, id)
+	} else {
+		org, err = orgs.FindBySlug(ctx, ref)
+	}
+	if errors.Is(err, ErrOrganizationNotFound) && role != "admin" {
+		return nil, "", ErrNotMember
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	if role == "admin" {
+		return org, "admin", nil
+	}
+	membership, err := orgs.FindMembership(ctx, org.ID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	return org, membership.Role, nil
+}
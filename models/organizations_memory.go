@@ -0,0 +1,151 @@
+package models
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type memoryOrganizationRepository struct {
+	mu            sync.RWMutex
+	organizations []Organization
+	memberships   []Membership
+}
+
+// NewMemoryOrganizationRepository keeps organizations in memory, for tests
+// and local experiments. It behaves like the MongoDB repository.
+func NewMemoryOrganizationRepository() OrganizationRepository {
+	return &memoryOrganizationRepository{}
+}
+
+func (r *memoryOrganizationRepository) find(match func(*Organization) bool) (*Organization, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i := range r.organizations {
+		if match(&r.organizations[i]) {
+			org := r.organizations[i]
+			return &org, nil
+		}
+	}
+	return nil, ErrOrganizationNotFound
+}
+
+func (r *memoryOrganizationRepository) FindByID(ctx context.Context, id bson.ObjectID) (*Organization, error) {
+	return r.find(func(o *Organization) bool { return o.ID == id })
+}
+
+func (r *memoryOrganizationRepository) FindBySlug(ctx context.Context, slug string) (*Organization, error) {
+	return r.find(func(o *Organization) bool { return o.Slug == slug })
+}
+
+func (r *memoryOrganizationRepository) List(ctx context.Context, ids []bson.ObjectID) ([]Organization, error) {
+	r.mu.RLock()
+	orgs := []Organization{}
+	for _, org := range r.organizations {
+		if ids == nil || slices.Contains(ids, org.ID) {
+			orgs = append(orgs, org)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.SliceStable(orgs, func(i, j int) bool { return orgs[i].Name < orgs[j].Name })
+	return orgs, nil
+}
+
+func (r *memoryOrganizationRepository) Create(ctx context.Context, org *Organization) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.organizations {
+		if existing.Slug == org.Slug {
+			return ErrOrganizationExists
+		}
+	}
+
+	created := *org
+	created.ID = bson.NewObjectID()
+	created.CreatedAt = now()
+	created.UpdatedAt = created.CreatedAt
+	r.organizations = append(r.organizations, created)
+
+	*org = created
+	return nil
+}
+
+// membership returns the index of a membership, or -1
+func (r *memoryOrganizationRepository) membership(orgID, userID bson.ObjectID) int {
+	return slices.IndexFunc(r.memberships, func(m Membership) bool {
+		return m.OrgID == orgID && m.UserID == userID
+	})
+}
+
+func (r *memoryOrganizationRepository) FindMembership(ctx context.Context, orgID, userID bson.ObjectID) (*Membership, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	i := r.membership(orgID, userID)
+	if i < 0 {
+		return nil, ErrNotMember
+	}
+	membership := r.memberships[i]
+	return &membership, nil
+}
+
+func (r *memoryOrganizationRepository) filterMemberships(match func(*Membership) bool) []Membership {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	memberships := []Membership{}
+	for i := range r.memberships {
+		if match(&r.memberships[i]) {
+			memberships = append(memberships, r.memberships[i])
+		}
+	}
+	return memberships
+}
+
+func (r *memoryOrganizationRepository) Members(ctx context.Context, orgID bson.ObjectID) ([]Membership, error) {
+	return r.filterMemberships(func(m *Membership) bool { return m.OrgID == orgID }), nil
+}
+
+func (r *memoryOrganizationRepository) UserMemberships(ctx context.Context, userID bson.ObjectID) ([]Membership, error) {
+	return r.filterMemberships(func(m *Membership) bool { return m.UserID == userID }), nil
+}
+
+func (r *memoryOrganizationRepository) SetMember(ctx context.Context, membership *Membership) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	updatedAt := now()
+	i := r.membership(membership.OrgID, membership.UserID)
+	if i < 0 {
+		r.memberships = append(r.memberships, Membership{
+			ID:        bson.NewObjectID(),
+			OrgID:     membership.OrgID,
+			UserID:    membership.UserID,
+			CreatedAt: updatedAt,
+		})
+		i = len(r.memberships) - 1
+	}
+	r.memberships[i].Role = membership.Role
+	r.memberships[i].UpdatedAt = updatedAt
+
+	*membership = r.memberships[i]
+	return nil
+}
+
+func (r *memoryOrganizationRepository) RemoveMember(ctx context.Context, orgID, userID bson.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := r.membership(orgID, userID)
+	if i < 0 {
+		return ErrNotMember
+	}
+	r.memberships = slices.Delete(r.memberships, i, i+1)
+	return nil
+}
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type mongoOrganizationRepository struct {
+	organizations *mongo.Collection
+	memberships   *mongo.Collection
+}
+
+// NewMongoOrganizationRepository stores organizations in the organizations
+// collection and their members in the memberships collection
+func NewMongoOrganizationRepository(db *mongo.Database) OrganizationRepository {
+	return &mongoOrganizationRepository{db.Collection("organizations"), db.Collection("memberships")}
+}
+
+func (r *mongoOrganizationRepository) findOne(ctx context.Context, filter bson.M) (*Organization, error) {
+	var org Organization
+	err := r.organizations.FindOne(ctx, filter).Decode(&org)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (r *mongoOrganizationRepository) FindByID(ctx context.Context, id bson.ObjectID) (*Organization, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *mongoOrganizationRepository) FindBySlug(ctx context.Context, slug string) (*Organization, error) {
+	return r.findOne(ctx, bson.M{"slug": slug})
+}
+
+func (r *mongoOrganizationRepository) List(ctx context.Context, ids []bson.ObjectID) ([]Organization, error) {
+	filter := bson.M{}
+	if ids != nil {
+		filter["_id"] = bson.M{"$in": ids}
+	}
+
+	cursor, err := r.organizations.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	orgs := []Organization{}
+	if err := cursor.All(ctx, &orgs); err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+func (r *mongoOrganizationRepository) Create(ctx context.Context, org *Organization) error {
+	created := *org
+	created.ID = bson.NewObjectID()
+	created.CreatedAt = now()
+	created.UpdatedAt = created.CreatedAt
+
+	if _, err := r.organizations.InsertOne(ctx, &created); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrOrganizationExists
+		}
+		return err
+	}
+
+	*org = created
+	return nil
+}
+
+func (r *mongoOrganizationRepository) FindMembership(ctx context.Context, orgID, userID bson.ObjectID) (*Membership, error) {
+	var membership Membership
+	err := r.memberships.FindOne(ctx, bson.M{"org_id": orgID, "user_id": userID}).Decode(&membership)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotMember
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+func (r *mongoOrganizationRepository) findMemberships(ctx context.Context, filter bson.M) ([]Membership, error) {
+	cursor, err := r.memberships.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	memberships := []Membership{}
+	if err := cursor.All(ctx, &memberships); err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
+
+func (r *mongoOrganizationRepository) Members(ctx context.Context, orgID bson.ObjectID) ([]Membership, error) {
+	return r.findMemberships(ctx, bson.M{"org_id": orgID})
+}
+
+func (r *mongoOrganizationRepository) UserMemberships(ctx context.Context, userID bson.ObjectID) ([]Membership, error) {
+	return r.findMemberships(ctx, bson.M{"user_id": userID})
+}
+
+func (r *mongoOrganizationRepository) SetMember(ctx context.Context, membership *Membership) error {
+	updatedAt := now()
+	update := bson.M{
+		"$set":         bson.M{"role": membership.Role, "updated_at": updatedAt},
+		"$setOnInsert": bson.M{"created_at": updatedAt},
+	}
+	filter := bson.M{"org_id": membership.OrgID, "user_id": membership.UserID}
+	upsert := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var stored Membership
+	if err := r.memberships.FindOneAndUpdate(ctx, filter, update, upsert).Decode(&stored); err != nil {
+		return err
+	}
+
+	*membership = stored
+	return nil
+}
+
+func (r *mongoOrganizationRepository) RemoveMember(ctx context.Context, orgID, userID bson.ObjectID) error {
+	result, err := r.memberships.DeleteOne(ctx, bson.M{"org_id": orgID, "user_id": userID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotMember
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrInvalidSort     = errors.New("invalid sort field")
+	ErrVersionConflict = errors.New("user was changed since it was read")
+)
+
+// UserSortFields are the fields users can be sorted by
+var UserSortFields = []string{"name", "email", "username", "role", "status"}
+
+// UserFilter selects users. Empty fields match everything; Search matches
+// part of the name, email or username, ignoring case. Deleted selects the
+// soft deleted users instead of the others.
+type UserFilter struct {
+	Role    string
+	Status  string
+	Search  string
+	Deleted bool
+}
+
+// ListOptions selects a page of users. Sort is one of UserSortFields,
+// prefixed with "-" for descending order; users are sorted by creation
+// otherwise. Page starts at 1.
+type ListOptions struct {
+	Filter UserFilter
+	Sort   string
+	Page   int
+	Size   int
+}
+
+// UserRepository stores users. Emails and usernames are compared ignoring
+// case. Lookups return ErrUserNotFound when no user matches, Create and
+// Update return ErrUserExists when the email or username belongs to another
+// user, deleted or not.
+//
+// Soft deleted users are left out of every lookup and of List and Count
+// unless UserFilter.Deleted is set; Restore brings them back. Create and
+// Update maintain the timestamps and the version. Update and Delete fail
+// with ErrVersionConflict when the stored user's version is not
+// user.Version, and on success leave the new version in user.
+type UserRepository interface {
+	FindByEmail(ctx context.Context, email string) (*User, error)
+	FindByUsername(ctx context.Context, username string) (*User, error)
+	FindByID(ctx context.Context, id bson.ObjectID) (*User, error)
+	List(ctx context.Context, opts ListOptions) ([]User, error)
+	Count(ctx context.Context, filter UserFilter) (int64, error)
+	Create(ctx context.Context, user *User) error
+	Update(ctx context.Context, user *User) error
+	Delete(ctx context.Context, user *User) error
+	Restore(ctx context.Context, id bson.ObjectID) (*User, error)
+}
+
+// now is the time stored in timestamps, at the millisecond precision of
+// MongoDB dates so every repository returns the same values
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// sortField splits a sort option into its field and direction
+func sortField(sort string) (string, bool, error) {
+	field, desc := sort, false
+	if len(field) > 0 && field[0] == '-' {
+		field, desc = field[1:], true
+	}
+	if field == "" {
+		return "", false, nil
+	}
+	for _, allowed := range UserSortFields {
+		if field == allowed {
+			return field, desc, nil
+		}
+	}
+	return "", false, ErrInvalidSort
+}
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/kenztech/go-api-starter/config"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Bootstrap modes, selected with bootstrap.mode
+const (
+	// BootstrapAuto creates the admin from the configured credentials when
+	// set, and otherwise prints a one-time setup token for POST /api/setup.
+	BootstrapAuto = "auto"
+	// BootstrapDisabled never creates an admin; use it in production.
+	BootstrapDisabled = "disabled"
+)
+
+type SetupToken struct {
+	ID        bson.ObjectID `bson:"_id,omitempty"`
+	TokenHash string        `bson:"token_hash"`
+	ExpiresAt time.Time     `bson:"expires_at"`
+	CreatedAt time.Time     `bson:"created_at"`
+}
+
+// AdminExists reports whether at least one admin user exists
+func AdminExists(ctx context.Context, users UserRepository) (bool, error) {
+	count, err := users.Count(ctx, UserFilter{Role: "admin"})
+	return count > 0, err
+}
+
+// ConsumeSetupToken deletes the setup token if it is valid and returns it,
+// or nil when it is not. A token can only be consumed once.
+func ConsumeSetupToken(ctx context.Context, db *mongo.Database, token string) (*SetupToken, error) {
+	filter := bson.M{
+		"token_hash": hashSetupToken(token),
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+
+	var consumed SetupToken
+	err := db.Collection("setup_tokens").FindOneAndDelete(ctx, filter).Decode(&consumed)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &consumed, nil
+}
+
+// RestoreSetupToken puts back a token consumed by a setup that failed, so
+// the operator can retry with it
+func RestoreSetupToken(ctx context.Context, db *mongo.Database, token *SetupToken) error {
+	_, err := db.Collection("setup_tokens").InsertOne(ctx, token)
+	return err
+}
+
+// BootstrapAdmin makes sure the first admin can be created when none exists
+func BootstrapAdmin(db *mongo.Database, cfg config.BootstrapConfig) {
+	if cfg.Mode == BootstrapDisabled {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	users := NewMongoUserRepository(db)
+	exists, err := AdminExists(ctx, users)
+	if err != nil {
+		slog.Error("Error checking for admin user", "error", err)
+		return
+	}
+	if exists {
+		return
+	}
+
+	if cfg.AdminEmail != "" && cfg.AdminPassword != "" {
+		createBootstrapAdmin(ctx, users, cfg)
+		return
+	}
+
+	issueSetupToken(ctx, db, cfg.SetupTokenTTL)
+}
+
+// createBootstrapAdmin creates the admin from configured credentials. The
+// password has to be changed on first login since it lives in the environment.
+func createBootstrapAdmin(ctx context.Context, users UserRepository, cfg config.BootstrapConfig) {
+	admin := User{
+		Name:               "Admin",
+		Role:               "admin",
+		Username:           cfg.AdminUsername,
+		Status:             "active",
+		Email:              cfg.AdminEmail,
+		MustChangePassword: true,
+	}
+
+	if err := CreateUser(ctx, users, &admin, cfg.AdminPassword); err != nil {
+		slog.Error("Error creating admin user", "error", err)
+	} else {
+		slog.Info("Admin user created successfully!", "email", cfg.AdminEmail)
+	}
+}
+
+// issueSetupToken replaces any previous setup token with a new one and prints
+// it. Only its hash is stored.
+func issueSetupToken(ctx context.Context, db *mongo.Database, ttl time.Duration) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		slog.Error("Error generating setup token", "error", err)
+		return
+	}
+	token := hex.EncodeToString(raw)
+
+	collection := db.Collection("setup_tokens")
+	if _, err := collection.DeleteMany(ctx, bson.M{}); err != nil {
+		slog.Error("Error removing old setup tokens", "error", err)
+		return
+	}
+
+	_, err := collection.InsertOne(ctx, SetupToken{
+		ID:        bson.NewObjectID(),
+		TokenHash: hashSetupToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		slog.Error("Error storing setup token", "error", err)
+		return
+	}
+
+	// The token is part of the message on purpose: it must reach the operator,
+	// while token attributes are redacted from the logs.
+	slog.Warn("No admin user exists. Create one with POST /api/setup using this one-time setup token: "+token, "valid_for", ttl)
+}
+
+func hashSetupToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kenztech/go-api-starter/metrics"
+	"github.com/kenztech/go-api-starter/utils"
+)
+
+var ErrUserExists = errors.New("email or username already in use")
+
+// CreateUser hashes the password and stores a new user, rejecting duplicate
+// emails and usernames with ErrUserExists.
+func CreateUser(ctx context.Context, users UserRepository, user *User, password string) error {
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	user.Password = hashedPassword
+	return users.Create(ctx, user)
+}
+
+// SetPassword hashes and stores a new password along with the given history.
+// mustChange forces the user to pick another password on next login. Every
+// token issued until now is revoked, so sessions opened with the old
+// password end.
+func SetPassword(ctx context.Context, users UserRepository, user *User, password string, history []string, mustChange bool) error {
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	updated := *user
+	updated.Password = hashedPassword
+	updated.PasswordHistory = history
+	updated.MustChangePassword = mustChange
+	updated.TokensRevokedAt = now()
+	if err := users.Update(ctx, &updated); err != nil {
+		return err
+	}
+
+	*user = updated
+	metrics.TokensRevoked.Inc()
+	return nil
+}
+
+// RevokeTokens invalidates every token issued to the user until now
+func RevokeTokens(ctx context.Context, users UserRepository, user *User) error {
+	updated := *user
+	updated.TokensRevokedAt = now()
+	if err := users.Update(ctx, &updated); err != nil {
+		return err
+	}
+
+	*user = updated
+	metrics.TokensRevoked.Inc()
+	return nil
+}
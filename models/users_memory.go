@@ -0,0 +1,255 @@
+package models
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type memoryUserRepository struct {
+	mu    sync.RWMutex
+	users []User
+}
+
+// NewMemoryUserRepository keeps users in memory, for tests and local
+// experiments. It behaves like the MongoDB repository.
+func NewMemoryUserRepository() UserRepository {
+	return &memoryUserRepository{}
+}
+
+func (r *memoryUserRepository) find(match func(*User) bool) (*User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i := range r.users {
+		if r.users[i].DeletedAt.IsZero() && match(&r.users[i]) {
+			user := cloneUser(r.users[i])
+			return &user, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+func (r *memoryUserRepository) FindByEmail(ctx context.Context, email string) (*User, error) {
+	return r.find(func(u *User) bool { return strings.EqualFold(u.Email, email) })
+}
+
+func (r *memoryUserRepository) FindByUsername(ctx context.Context, username string) (*User, error) {
+	if username == "" {
+		return nil, ErrUserNotFound
+	}
+	return r.find(func(u *User) bool { return strings.EqualFold(u.Username, username) })
+}
+
+func (r *memoryUserRepository) FindByID(ctx context.Context, id bson.ObjectID) (*User, error) {
+	return r.find(func(u *User) bool { return u.ID == id })
+}
+
+func (r *memoryUserRepository) List(ctx context.Context, opts ListOptions) ([]User, error) {
+	field, desc, err := sortField(opts.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	users := []User{}
+	for _, user := range r.users {
+		if matchesFilter(&user, opts.Filter) {
+			users = append(users, cloneUser(user))
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.SliceStable(users, func(i, j int) bool {
+		a := strings.ToLower(sortValue(&users[i], field))
+		b := strings.ToLower(sortValue(&users[j], field))
+		if a != b {
+			return (a < b) != desc
+		}
+		return users[i].ID.Hex() < users[j].ID.Hex()
+	})
+
+	if opts.Size > 0 {
+		start := 0
+		if opts.Page > 1 {
+			start = (opts.Page - 1) * opts.Size
+		}
+		if start > len(users) {
+			start = len(users)
+		}
+		end := start + opts.Size
+		if end > len(users) {
+			end = len(users)
+		}
+		users = users[start:end]
+	}
+	return users, nil
+}
+
+func (r *memoryUserRepository) Count(ctx context.Context, filter UserFilter) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for i := range r.users {
+		if matchesFilter(&r.users[i], filter) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, user *User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.taken(user) {
+		return ErrUserExists
+	}
+
+	created := cloneUser(*user)
+	if created.ID.IsZero() {
+		created.ID = bson.NewObjectID()
+	}
+	created.CreatedAt = now()
+	created.UpdatedAt = created.CreatedAt
+	created.DeletedAt = time.Time{}
+	created.Version = 1
+
+	r.users = append(r.users, created)
+	*user = cloneUser(created)
+	return nil
+}
+
+func (r *memoryUserRepository) Update(ctx context.Context, user *User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := r.get(user.ID)
+	if stored == nil {
+		return ErrUserNotFound
+	}
+	if stored.Version != user.Version {
+		return ErrVersionConflict
+	}
+	if r.taken(user) {
+		return ErrUserExists
+	}
+
+	updated := cloneUser(*user)
+	updated.UpdatedAt = now()
+	updated.DeletedAt = time.Time{}
+	updated.Version++
+
+	*stored = updated
+	*user = cloneUser(updated)
+	return nil
+}
+
+// Delete soft deletes the user
+func (r *memoryUserRepository) Delete(ctx context.Context, user *User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := r.get(user.ID)
+	if stored == nil {
+		return ErrUserNotFound
+	}
+	if stored.Version != user.Version {
+		return ErrVersionConflict
+	}
+
+	stored.DeletedAt = now()
+	stored.UpdatedAt = stored.DeletedAt
+	stored.Version++
+	*user = cloneUser(*stored)
+	return nil
+}
+
+func (r *memoryUserRepository) Restore(ctx context.Context, id bson.ObjectID) (*User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.users {
+		stored := &r.users[i]
+		if stored.ID == id && !stored.DeletedAt.IsZero() {
+			stored.DeletedAt = time.Time{}
+			stored.UpdatedAt = now()
+			stored.Version++
+
+			user := cloneUser(*stored)
+			return &user, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+// get returns the stored user with the given ID unless it is deleted
+func (r *memoryUserRepository) get(id bson.ObjectID) *User {
+	for i := range r.users {
+		if r.users[i].ID == id && r.users[i].DeletedAt.IsZero() {
+			return &r.users[i]
+		}
+	}
+	return nil
+}
+
+// taken reports whether another user has the same email or username,
+// ignoring case like the MongoDB indexes
+func (r *memoryUserRepository) taken(user *User) bool {
+	for i := range r.users {
+		other := &r.users[i]
+		if other.ID == user.ID && !user.ID.IsZero() {
+			continue
+		}
+		if strings.EqualFold(other.Email, user.Email) || (user.Username != "" && strings.EqualFold(other.Username, user.Username)) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesFilter(user *User, filter UserFilter) bool {
+	if user.DeletedAt.IsZero() == filter.Deleted {
+		return false
+	}
+	if filter.Role != "" && user.Role != filter.Role {
+		return false
+	}
+	if filter.Status != "" && user.Status != filter.Status {
+		return false
+	}
+	if filter.Search != "" {
+		search := strings.ToLower(filter.Search)
+		return strings.Contains(strings.ToLower(user.Name), search) ||
+			strings.Contains(strings.ToLower(user.Email), search) ||
+			strings.Contains(strings.ToLower(user.Username), search)
+	}
+	return true
+}
+
+func sortValue(user *User, field string) string {
+	switch field {
+	case "name":
+		return user.Name
+	case "email":
+		return user.Email
+	case "username":
+		return user.Username
+	case "role":
+		return user.Role
+	case "status":
+		return user.Status
+	}
+	return ""
+}
+
+// cloneUser copies the user so callers cannot modify the stored one
+func cloneUser(user User) User {
+	user.PasswordHistory = append([]string(nil), user.PasswordHistory...)
+	return user
+}
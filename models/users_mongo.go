@@ -0,0 +1,247 @@
+package models
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// userCollation compares emails and usernames ignoring case. Queries on them
+// must use it to match the unique indexes.
+var userCollation = &options.Collation{Locale: "en", Strength: 2}
+
+type mongoUserRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoUserRepository stores users in the users collection
+func NewMongoUserRepository(db *mongo.Database) UserRepository {
+	return &mongoUserRepository{db.Collection("users")}
+}
+
+// notDeleted leaves soft deleted users out of a query
+var notDeleted = bson.M{"deleted_at": bson.M{"$exists": false}}
+
+func (r *mongoUserRepository) findOne(ctx context.Context, filter bson.M) (*User, error) {
+	filter = bson.M{"$and": []bson.M{filter, notDeleted}}
+
+	var user User
+	err := r.collection.FindOne(ctx, filter, options.FindOne().SetCollation(userCollation)).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *mongoUserRepository) FindByEmail(ctx context.Context, email string) (*User, error) {
+	return r.findOne(ctx, bson.M{"email": email})
+}
+
+func (r *mongoUserRepository) FindByUsername(ctx context.Context, username string) (*User, error) {
+	// Usernames are optional: an empty one must not match users without one.
+	if username == "" {
+		return nil, ErrUserNotFound
+	}
+	return r.findOne(ctx, bson.M{"username": username})
+}
+
+func (r *mongoUserRepository) FindByID(ctx context.Context, id bson.ObjectID) (*User, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *mongoUserRepository) List(ctx context.Context, opts ListOptions) ([]User, error) {
+	field, desc, err := sortField(opts.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	// ObjectIDs start with their creation time, so _id sorts by creation and
+	// breaks ties between equal sort values.
+	sort := bson.D{}
+	if field != "" {
+		order := 1
+		if desc {
+			order = -1
+		}
+		sort = append(sort, bson.E{Key: field, Value: order})
+	}
+	sort = append(sort, bson.E{Key: "_id", Value: 1})
+
+	find := options.Find().SetSort(sort).SetCollation(userCollation)
+	if opts.Size > 0 {
+		find.SetLimit(int64(opts.Size))
+		if opts.Page > 1 {
+			find.SetSkip(int64((opts.Page - 1) * opts.Size))
+		}
+	}
+
+	cursor, err := r.collection.Find(ctx, userFilter(opts.Filter), find)
+	if err != nil {
+		return nil, err
+	}
+
+	users := []User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *mongoUserRepository) Count(ctx context.Context, filter UserFilter) (int64, error) {
+	return r.collection.CountDocuments(ctx, userFilter(filter), options.Count().SetCollation(userCollation))
+}
+
+func (r *mongoUserRepository) Create(ctx context.Context, user *User) error {
+	if err := r.checkUnique(ctx, user); err != nil {
+		return err
+	}
+
+	created := *user
+	if created.ID.IsZero() {
+		created.ID = bson.NewObjectID()
+	}
+	created.CreatedAt = now()
+	created.UpdatedAt = created.CreatedAt
+	created.DeletedAt = time.Time{}
+	created.Version = 1
+
+	if _, err := r.collection.InsertOne(ctx, created); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrUserExists
+		}
+		return err
+	}
+
+	*user = created
+	return nil
+}
+
+func (r *mongoUserRepository) Update(ctx context.Context, user *User) error {
+	if err := r.checkUnique(ctx, user); err != nil {
+		return err
+	}
+
+	updated := *user
+	updated.UpdatedAt = now()
+	updated.DeletedAt = time.Time{}
+	updated.Version++
+
+	// The version in the filter makes the replacement a compare-and-swap.
+	filter := bson.M{"_id": user.ID, "version": user.Version, "deleted_at": bson.M{"$exists": false}}
+	result, err := r.collection.ReplaceOne(ctx, filter, updated)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrUserExists
+		}
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return r.missing(ctx, user.ID)
+	}
+
+	*user = updated
+	return nil
+}
+
+// Delete soft deletes the user
+func (r *mongoUserRepository) Delete(ctx context.Context, user *User) error {
+	deleted := *user
+	deleted.DeletedAt = now()
+	deleted.UpdatedAt = deleted.DeletedAt
+	deleted.Version++
+
+	update := bson.M{"$set": bson.M{
+		"deleted_at": deleted.DeletedAt,
+		"updated_at": deleted.UpdatedAt,
+		"version":    deleted.Version,
+	}}
+	filter := bson.M{"_id": user.ID, "version": user.Version, "deleted_at": bson.M{"$exists": false}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return r.missing(ctx, user.ID)
+	}
+
+	*user = deleted
+	return nil
+}
+
+func (r *mongoUserRepository) Restore(ctx context.Context, id bson.ObjectID) (*User, error) {
+	update := bson.M{
+		"$unset": bson.M{"deleted_at": ""},
+		"$set":   bson.M{"updated_at": now()},
+		"$inc":   bson.M{"version": 1},
+	}
+	filter := bson.M{"_id": id, "deleted_at": bson.M{"$exists": true}}
+
+	var user User
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// missing tells why an update matched nothing: the user is gone, or it was
+// updated since it was read
+func (r *mongoUserRepository) missing(ctx context.Context, id bson.ObjectID) error {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"$and": []bson.M{{"_id": id}, notDeleted}})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrVersionConflict
+	}
+	return ErrUserNotFound
+}
+
+// checkUnique gives a clear error before the unique indexes reject the write
+func (r *mongoUserRepository) checkUnique(ctx context.Context, user *User) error {
+	filter := bson.M{"email": user.Email}
+	if user.Username != "" {
+		filter = bson.M{"$or": []bson.M{{"email": user.Email}, {"username": user.Username}}}
+	}
+	if !user.ID.IsZero() {
+		filter = bson.M{"$and": []bson.M{filter, {"_id": bson.M{"$ne": user.ID}}}}
+	}
+
+	count, err := r.collection.CountDocuments(ctx, filter, options.Count().SetCollation(userCollation))
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrUserExists
+	}
+	return nil
+}
+
+func userFilter(filter UserFilter) bson.M {
+	query := bson.M{"deleted_at": bson.M{"$exists": filter.Deleted}}
+	if filter.Role != "" {
+		query["role"] = filter.Role
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.Search != "" {
+		pattern := bson.Regex{Pattern: regexp.QuoteMeta(filter.Search), Options: "i"}
+		query["$or"] = []bson.M{
+			{"name": pattern},
+			{"email": pattern},
+			{"username": pattern},
+		}
+	}
+	return query
+}
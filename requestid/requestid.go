@@ -0,0 +1,71 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// Header carries the request ID on requests, responses and outbound calls
+const Header = "X-Request-ID"
+
+// maxLength bounds IDs accepted from clients
+const maxLength = 128
+
+type contextKey struct{}
+
+// NewContext returns a context carrying the request ID
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID, or "" outside of a request
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// New generates a random request ID
+func New() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Valid reports whether an incoming ID is safe to reuse in headers and logs
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Transport propagates the request ID of the request context to outbound
+// HTTP calls, such as webhooks.
+type Transport struct {
+	Base http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	if id := FromContext(r.Context()); id != "" && r.Header.Get(Header) == "" {
+		r = r.Clone(r.Context())
+		r.Header.Set(Header, id)
+	}
+	return base.RoundTrip(r)
+}
@@ -0,0 +1,36 @@
+package resource
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Base holds the fields every resource document has. Embed it inline:
+//
+//	type Widget struct {
+//		resource.Base `bson:",inline"`
+//		Name string `bson:"name" json:"name"`
+//	}
+//
+// The fields are maintained by the store: don't set them from requests.
+// OrgID is only set on documents of scoped resources; clients know it from
+// the request.
+type Base struct {
+	ID        bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrgID     bson.ObjectID `bson:"org_id,omitempty" json:"-"`
+	CreatedAt time.Time     `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time     `bson:"updated_at" json:"updated_at"`
+	DeletedAt *time.Time    `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	Version   int64         `bson:"version" json:"version"`
+}
+
+func (b *Base) base() *Base {
+	return b
+}
+
+// Document is a pointer to a model embedding Base
+type Document[T any] interface {
+	*T
+	base() *Base
+}
@@ -0,0 +1,70 @@
+package resource
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type widget struct {
+	Base  `bson:",inline"`
+	Name  string `bson:"name" json:"name"`
+	Count int    `bson:"count" json:"count"`
+}
+
+func TestBaseInlineCodec(t *testing.T) {
+	// BSON stores datetimes in milliseconds and decodes them in UTC
+	now := time.Date(2024, 3, 14, 15, 9, 26, 535_000_000, time.UTC)
+	deleted := now.Add(time.Hour)
+
+	tests := []struct {
+		name   string
+		widget widget
+		absent []string
+	}{
+		{"new", widget{Name: "sprocket"}, []string{"_id", "org_id", "deleted_at"}},
+		{"stored", widget{
+			Base:  Base{ID: bson.NewObjectID(), CreatedAt: now, UpdatedAt: now, Version: 1},
+			Name:  "sprocket",
+			Count: 3,
+		}, []string{"org_id", "deleted_at"}},
+		{"scoped and deleted", widget{
+			Base:  Base{ID: bson.NewObjectID(), OrgID: bson.NewObjectID(), CreatedAt: now, UpdatedAt: now, DeletedAt: &deleted, Version: 2},
+			Name:  "sprocket",
+			Count: 3,
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := bson.Marshal(tt.widget)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got widget
+			if err := bson.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.widget) {
+				t.Fatalf("round trip:\n got  %+v\n want %+v", got, tt.widget)
+			}
+
+			// The base fields sit at the top level of the document
+			doc := bson.Raw(data)
+			for _, key := range []string{"created_at", "updated_at", "version", "name", "count"} {
+				if _, err := doc.LookupErr(key); err != nil {
+					t.Errorf("key %q missing from %s", key, doc)
+				}
+			}
+			if _, err := doc.LookupErr("base"); err == nil {
+				t.Errorf("base should be inlined in %s", doc)
+			}
+			for _, key := range tt.absent {
+				if _, err := doc.LookupErr(key); err == nil {
+					t.Errorf("key %q should be omitted from %s", key, doc)
+				}
+			}
+		})
+	}
+}
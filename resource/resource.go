@@ -0,0 +1,349 @@
+// Package resource serves CRUD APIs for collections the way the users API
+// works: paginated and filtered lists, soft delete with restore, updates
+// guarded by ETags, and the same error responses.
+package resource
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kenztech/go-api-starter/apperror"
+	"github.com/kenztech/go-api-starter/i18n"
+	"github.com/kenztech/go-api-starter/models/api"
+	"github.com/kenztech/go-api-starter/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// Permissions names the permission needed for each action. Empty names
+// default to "<collection>.<action>", e.g. "widgets.list".
+type Permissions struct {
+	List    string
+	Get     string
+	Create  string
+	Update  string
+	Delete  string
+	Restore string
+}
+
+// Config describes a resource of model T, created from requests of type C
+// and updated from requests of type U. Requests are validated with their
+// validate tags before reaching New and Apply.
+type Config[T, C, U any] struct {
+	// Collection holds the documents and names the default permissions.
+	Collection  string
+	Permissions Permissions
+
+	// Scoped resources belong to organizations: their routes must be
+	// behind Tenancy.Resolve, and only ever see the tenant's documents.
+	Scoped bool
+
+	// Filters are the fields lists can be filtered on by equality, given
+	// as query parameters named after their bson field.
+	Filters []string
+
+	// Sort are the fields lists can be sorted on.
+	Sort []string
+
+	// New builds the document to create from a request.
+	New func(request C) T
+
+	// Apply changes a document according to an update request.
+	Apply func(item *T, request U)
+}
+
+// Authorizer makes middlewares requiring a permission, such as
+// middlewares.Permissions
+type Authorizer interface {
+	Require(permission string) func(http.Handler) http.Handler
+}
+
+// Resource mounts the CRUD routes of a collection
+type Resource[T, C, U any, P Document[T]] struct {
+	cfg   Config[T, C, U]
+	store *store[T, P]
+}
+
+// New serves the resource described by cfg from the database
+func New[T, C, U any, P Document[T]](db *mongo.Database, cfg Config[T, C, U]) *Resource[T, C, U, P] {
+	p := &cfg.Permissions
+	for action, name := range map[string]*string{
+		"list": &p.List, "get": &p.Get, "create": &p.Create,
+		"update": &p.Update, "delete": &p.Delete, "restore": &p.Restore,
+	} {
+		if *name == "" {
+			*name = cfg.Collection + "." + action
+		}
+	}
+	return &Resource[T, C, U, P]{cfg, &store[T, P]{db.Collection(cfg.Collection), cfg.Scoped}}
+}
+
+// Routes mounts the resource on r. Users must be authenticated by an
+// earlier middleware; each route then requires its permission.
+func (res *Resource[T, C, U, P]) Routes(r chi.Router, permissions Authorizer) {
+	p := res.cfg.Permissions
+	r.With(permissions.Require(p.List)).Get("/", utils.JSON(http.StatusOK, res.List))
+	r.With(permissions.Require(p.Get)).Get("/{id}", utils.JSON(http.StatusOK, res.Get))
+	r.With(permissions.Require(p.Create)).Post("/", utils.JSON(http.StatusCreated, res.Create))
+	r.With(permissions.Require(p.Update)).Put("/{id}", utils.JSON(http.StatusOK, res.Update))
+	r.With(permissions.Require(p.Delete)).Delete("/{id}", utils.JSON(http.StatusOK, res.Delete))
+	r.With(permissions.Require(p.Restore)).Post("/{id}/restore", utils.JSON(http.StatusOK, res.Restore))
+}
+
+// ListResponse is a page of documents
+type ListResponse[T any] struct {
+	Success    bool           `json:"success"`
+	Items      []T            `json:"items"`
+	Pagination api.Pagination `json:"pagination"`
+}
+
+// ItemResponse is a single document
+type ItemResponse[T any] struct {
+	Success bool `json:"success"`
+	Item    T    `json:"item"`
+
+	etag string
+}
+
+// ETag identifies the document's current version
+func (r ItemResponse[T]) ETag() string {
+	return r.etag
+}
+
+// List lists documents a page at a time. Query parameters: page, size, sort
+// (a field, "-" prefixed for descending order), deleted, which lists the
+// deleted documents instead, and the configured filters.
+func (res *Resource[T, C, U, P]) List(r *http.Request) (ListResponse[T], error) {
+	query := r.URL.Query()
+
+	filter := bson.M{"deleted_at": bson.M{"$exists": query.Get("deleted") == "true"}}
+	for _, field := range res.cfg.Filters {
+		if value := query.Get(field); value != "" {
+			filter[field] = value
+		}
+	}
+
+	sort, err := res.sort(query.Get("sort"))
+	if err != nil {
+		return ListResponse[T]{}, err
+	}
+
+	page := queryInt(r, "page", 1)
+	if page < 1 {
+		page = 1
+	}
+	size := queryInt(r, "size", defaultPageSize)
+	if size < 1 {
+		size = defaultPageSize
+	} else if size > maxPageSize {
+		size = maxPageSize
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	items, err := res.store.list(ctx, filter, sort, page, size)
+	if err != nil {
+		return ListResponse[T]{}, err
+	}
+
+	total, err := res.store.count(ctx, filter)
+	if err != nil {
+		return ListResponse[T]{}, err
+	}
+
+	return ListResponse[T]{
+		Success: true,
+		Items:   items,
+		Pagination: api.Pagination{
+			Page:       page,
+			Size:       size,
+			TotalCount: total,
+			TotalPages: int((total + int64(size) - 1) / int64(size)),
+		},
+	}, nil
+}
+
+func (res *Resource[T, C, U, P]) Get(r *http.Request) (ItemResponse[T], error) {
+	id, err := utils.URLParamID(r, "id")
+	if err != nil {
+		return ItemResponse[T]{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	item, err := res.store.get(ctx, id)
+	if err != nil {
+		return ItemResponse[T]{}, storeError(err)
+	}
+
+	return itemResponse(item), nil
+}
+
+func (res *Resource[T, C, U, P]) Create(r *http.Request) (ItemResponse[T], error) {
+	var request C
+	if err := utils.DecodeJSON(r, &request); err != nil {
+		return ItemResponse[T]{}, err
+	}
+
+	item := res.cfg.New(request)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := res.store.create(ctx, &item); err != nil {
+		return ItemResponse[T]{}, storeError(err)
+	}
+
+	return itemResponse(P(&item)), nil
+}
+
+// Update changes a document. The request must carry the document's ETag in
+// If-Match.
+func (res *Resource[T, C, U, P]) Update(r *http.Request) (ItemResponse[T], error) {
+	id, err := utils.URLParamID(r, "id")
+	if err != nil {
+		return ItemResponse[T]{}, err
+	}
+
+	var request U
+	if err := utils.DecodeJSON(r, &request); err != nil {
+		return ItemResponse[T]{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	item, err := res.store.get(ctx, id)
+	if err != nil {
+		return ItemResponse[T]{}, storeError(err)
+	}
+	if err := utils.CheckIfMatch(r, etag(item)); err != nil {
+		return ItemResponse[T]{}, err
+	}
+
+	// Apply may not change the fields the store maintains.
+	base := *item.base()
+	res.cfg.Apply((*T)(item), request)
+	*item.base() = base
+
+	if err := res.store.update(ctx, item); err != nil {
+		return ItemResponse[T]{}, storeError(err)
+	}
+
+	return itemResponse(item), nil
+}
+
+// Delete soft deletes a document. The request must carry the document's
+// ETag in If-Match.
+func (res *Resource[T, C, U, P]) Delete(r *http.Request) (api.SuccessResponse, error) {
+	id, err := utils.URLParamID(r, "id")
+	if err != nil {
+		return api.SuccessResponse{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	item, err := res.store.get(ctx, id)
+	if err != nil {
+		return api.SuccessResponse{}, storeError(err)
+	}
+	if err := utils.CheckIfMatch(r, etag(item)); err != nil {
+		return api.SuccessResponse{}, err
+	}
+
+	if err := res.store.delete(ctx, item); err != nil {
+		return api.SuccessResponse{}, storeError(err)
+	}
+
+	return api.SuccessResponse{Success: true, Message: "Deleted."}, nil
+}
+
+// Restore brings back a deleted document
+func (res *Resource[T, C, U, P]) Restore(r *http.Request) (ItemResponse[T], error) {
+	id, err := utils.URLParamID(r, "id")
+	if err != nil {
+		return ItemResponse[T]{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	item, err := res.store.restore(ctx, id)
+	if err != nil {
+		return ItemResponse[T]{}, storeError(err)
+	}
+
+	return itemResponse(item), nil
+}
+
+// sort parses the sort query parameter, a field "-" prefixed for descending
+// order
+func (res *Resource[T, C, U, P]) sort(sort string) (bson.D, error) {
+	if sort == "" {
+		return bson.D{}, nil
+	}
+
+	field, order := strings.TrimPrefix(sort, "-"), 1
+	if field != sort {
+		order = -1
+	}
+	if !slices.Contains(res.cfg.Sort, field) {
+		fields := strings.Join(res.cfg.Sort, " ")
+		return nil, apperror.Validation([]api.FieldError{{
+			Field:   "sort",
+			Rule:    "oneof",
+			Param:   fields,
+			Message: i18n.RuleMessage(i18n.Default(), "oneof", "sort", fields),
+		}})
+	}
+	return bson.D{{Key: field, Value: order}}, nil
+}
+
+// storeError turns store errors into errors for clients
+func storeError(err error) error {
+	switch {
+	case errors.Is(err, errNotFound):
+		return apperror.ErrNotFound
+	case errors.Is(err, errDuplicate):
+		return apperror.ErrConflict
+	case errors.Is(err, errVersionConflict):
+		return apperror.ErrVersionConflict.Wrap(err)
+	case errors.Is(err, errNoTenant):
+		return apperror.Internal(err)
+	}
+	return err
+}
+
+func itemResponse[T any, P Document[T]](item P) ItemResponse[T] {
+	return ItemResponse[T]{Success: true, Item: *item, etag: etag(item)}
+}
+
+// etag changes with every update of the document, like the users' ETag
+func etag[T any, P Document[T]](item P) string {
+	b := item.base()
+	return `"` + b.ID.Hex() + "-" + strconv.FormatInt(b.Version, 10) + `"`
+}
+
+// queryInt returns an integer query parameter, or def when it is missing or
+// not a number
+func queryInt(r *http.Request, name string, def int) int {
+	n, err := strconv.Atoi(r.URL.Query().Get(name))
+	if err != nil {
+		return def
+	}
+	return n
+}
@@ -0,0 +1,215 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kenztech/go-api-starter/tenant"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	errNotFound        = errors.New("document not found")
+	errDuplicate       = errors.New("duplicate document")
+	errVersionConflict = errors.New("document was changed since it was read")
+
+	// errNoTenant means a scoped resource is mounted without
+	// Tenancy.Resolve, which is a routing mistake rather than a client error
+	errNoTenant = errors.New("no tenant in request context")
+)
+
+// notDeleted leaves soft deleted documents out of a query
+var notDeleted = bson.M{"deleted_at": bson.M{"$exists": false}}
+
+// store keeps the documents of a resource in a collection. Like the user
+// repository, it soft deletes and makes updates a compare-and-swap on the
+// version. The documents of a scoped store belong to organizations: every
+// query is restricted to the tenant of the request context.
+type store[T any, P Document[T]] struct {
+	collection *mongo.Collection
+	scoped     bool
+}
+
+// scope restricts a filter to the tenant's documents
+func (s *store[T, P]) scope(ctx context.Context, filter bson.M) (bson.M, error) {
+	if !s.scoped {
+		return filter, nil
+	}
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, errNoTenant
+	}
+	return bson.M{"$and": []bson.M{filter, {"org_id": t.OrgID}}}, nil
+}
+
+func (s *store[T, P]) list(ctx context.Context, filter bson.M, sort bson.D, page, size int) ([]T, error) {
+	// ObjectIDs start with their creation time, so _id sorts by creation and
+	// breaks ties between equal sort values.
+	sort = append(sort, bson.E{Key: "_id", Value: 1})
+	find := options.Find().SetSort(sort).SetLimit(int64(size)).SetSkip(int64((page - 1) * size))
+
+	filter, err := s.scope(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := s.collection.Find(ctx, filter, find)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []T{}
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (s *store[T, P]) count(ctx context.Context, filter bson.M) (int64, error) {
+	filter, err := s.scope(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return s.collection.CountDocuments(ctx, filter)
+}
+
+func (s *store[T, P]) get(ctx context.Context, id bson.ObjectID) (P, error) {
+	filter, err := s.scope(ctx, bson.M{"$and": []bson.M{{"_id": id}, notDeleted}})
+	if err != nil {
+		return nil, err
+	}
+
+	item := P(new(T))
+	err = s.collection.FindOne(ctx, filter).Decode(item)
+	if err == mongo.ErrNoDocuments {
+		return nil, errNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *store[T, P]) create(ctx context.Context, item P) error {
+	b := item.base()
+	b.OrgID = bson.ObjectID{}
+	if s.scoped {
+		t, ok := tenant.FromContext(ctx)
+		if !ok {
+			return errNoTenant
+		}
+		b.OrgID = t.OrgID
+	}
+	b.ID = bson.NewObjectID()
+	b.CreatedAt = now()
+	b.UpdatedAt = b.CreatedAt
+	b.DeletedAt = nil
+	b.Version = 1
+
+	if _, err := s.collection.InsertOne(ctx, item); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return errDuplicate
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *store[T, P]) update(ctx context.Context, item P) error {
+	b := item.base()
+	version := b.Version
+
+	updated := *item
+	ub := P(&updated).base()
+	ub.UpdatedAt = now()
+	ub.Version++
+
+	filter, err := s.scope(ctx, bson.M{"_id": b.ID, "version": version, "deleted_at": bson.M{"$exists": false}})
+	if err != nil {
+		return err
+	}
+	result, err := s.collection.ReplaceOne(ctx, filter, P(&updated))
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return errDuplicate
+		}
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return s.missing(ctx, b.ID)
+	}
+
+	*item = updated
+	return nil
+}
+
+// delete soft deletes the document
+func (s *store[T, P]) delete(ctx context.Context, item P) error {
+	b := item.base()
+	deletedAt := now()
+
+	filter, err := s.scope(ctx, bson.M{"_id": b.ID, "version": b.Version, "deleted_at": bson.M{"$exists": false}})
+	if err != nil {
+		return err
+	}
+	update := bson.M{"$set": bson.M{"deleted_at": deletedAt, "updated_at": deletedAt, "version": b.Version + 1}}
+	result, err := s.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return s.missing(ctx, b.ID)
+	}
+
+	b.DeletedAt = &deletedAt
+	b.UpdatedAt = deletedAt
+	b.Version++
+	return nil
+}
+
+func (s *store[T, P]) restore(ctx context.Context, id bson.ObjectID) (P, error) {
+	update := bson.M{
+		"$unset": bson.M{"deleted_at": ""},
+		"$set":   bson.M{"updated_at": now()},
+		"$inc":   bson.M{"version": 1},
+	}
+	filter, err := s.scope(ctx, bson.M{"_id": id, "deleted_at": bson.M{"$exists": true}})
+	if err != nil {
+		return nil, err
+	}
+
+	item := P(new(T))
+	err = s.collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(item)
+	if err == mongo.ErrNoDocuments {
+		return nil, errNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// missing tells why a write matched nothing: the document is gone, or it was
+// changed since it was read
+func (s *store[T, P]) missing(ctx context.Context, id bson.ObjectID) error {
+	filter, err := s.scope(ctx, bson.M{"$and": []bson.M{{"_id": id}, notDeleted}})
+	if err != nil {
+		return err
+	}
+	count, err := s.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errVersionConflict
+	}
+	return errNotFound
+}
+
+// now is the time stored in timestamps, at the millisecond precision of
+// MongoDB dates
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// AdminServer serves internal endpoints, such as metrics, on their own
+// address. It runs as a worker so it keeps answering while the main server
+// drains and stops only once the API is down.
+type AdminServer struct {
+	http *http.Server
+}
+
+func NewAdminServer(addr string, handler http.Handler) *AdminServer {
+	return &AdminServer{
+		http: &http.Server{
+			Addr:              addr,
+			Handler:           handler,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+	}
+}
+
+func (a *AdminServer) Run(ctx context.Context) {
+	errCh := make(chan error, 1)
+	go func() {
+		slog.Info("Admin server starting", "addr", a.http.Addr)
+		errCh <- a.http.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		// Returning stops the worker, which fails the readiness check.
+		if !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Admin server failed", "error", err)
+		}
+		return
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := a.http.Shutdown(shutdownCtx); err != nil {
+		a.http.Close()
+	}
+	slog.Info("Admin server stopped")
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kenztech/go-api-starter/config"
+)
+
+// Worker is a background task that runs until its context is cancelled
+type Worker interface {
+	Run(ctx context.Context)
+}
+
+// WorkerFunc adapts a function to the Worker interface
+type WorkerFunc func(ctx context.Context)
+
+func (f WorkerFunc) Run(ctx context.Context) {
+	f(ctx)
+}
+
+// Server runs the HTTP server and background workers, and shuts them down in
+// order: flip readiness, wait for the drain delay, let in-flight requests
+// finish, then stop the workers.
+type Server struct {
+	cfg     config.ServerConfig
+	http    *http.Server
+	workers []Worker
+	running atomic.Int32
+	ready   atomic.Bool
+}
+
+func New(cfg config.ServerConfig, handler http.Handler) *Server {
+	s := &Server{cfg: cfg}
+	s.http = &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           s.closeWhileDraining(handler),
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+	return s
+}
+
+// AddWorker registers a background worker started by Run
+func (s *Server) AddWorker(w Worker) {
+	s.workers = append(s.workers, w)
+}
+
+// Ready reports whether the server accepts new traffic
+func (s *Server) Ready() bool {
+	return s.ready.Load()
+}
+
+// CheckReady fails while the server is starting or shutting down
+func (s *Server) CheckReady(ctx context.Context) error {
+	if !s.Ready() {
+		return errors.New("server is not accepting traffic")
+	}
+	return nil
+}
+
+// CheckWorkers fails when a background worker has stopped
+func (s *Server) CheckWorkers(ctx context.Context) error {
+	if running := int(s.running.Load()); running < len(s.workers) {
+		return fmt.Errorf("%d of %d background workers running", running, len(s.workers))
+	}
+	return nil
+}
+
+// Run serves until ctx is cancelled, then shuts down gracefully
+func (s *Server) Run(ctx context.Context) error {
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	var wg sync.WaitGroup
+	for _, w := range s.workers {
+		wg.Add(1)
+		s.running.Add(1)
+		go func(w Worker) {
+			defer wg.Done()
+			defer s.running.Add(-1)
+			w.Run(workerCtx)
+		}(w)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		slog.Info("Server starting", "port", s.cfg.Port)
+		errCh <- s.http.ListenAndServe()
+	}()
+	s.ready.Store(true)
+
+	var serveErr error
+	select {
+	case err := <-errCh:
+		serveErr = err
+	case <-ctx.Done():
+		serveErr = s.shutdown()
+	}
+
+	stopWorkers()
+	wg.Wait()
+	slog.Info("Background workers stopped")
+
+	if errors.Is(serveErr, http.ErrServerClosed) {
+		return nil
+	}
+	return serveErr
+}
+
+func (s *Server) shutdown() error {
+	// Report not ready first so load balancers stop sending new traffic.
+	s.ready.Store(false)
+	slog.Info("Shutting down, draining", "drain_delay", s.cfg.DrainDelay)
+	time.Sleep(s.cfg.DrainDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := s.http.Shutdown(ctx); err != nil {
+		slog.Error("In-flight requests did not finish in time", "shutdown_timeout", s.cfg.ShutdownTimeout, "error", err)
+		s.http.Close()
+		return err
+	}
+	slog.Info("HTTP server stopped")
+	return nil
+}
+
+// closeWhileDraining asks keep-alive clients to reconnect elsewhere once the
+// server is no longer ready.
+func (s *Server) closeWhileDraining(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.Ready() {
+			w.Header().Set("Connection", "close")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
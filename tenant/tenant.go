@@ -0,0 +1,27 @@
+// Package tenant carries the organization a request acts for
+package tenant
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Tenant is the organization of a request and the user's role in it
+type Tenant struct {
+	OrgID bson.ObjectID
+	Role  string
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying the tenant
+func NewContext(ctx context.Context, t Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tenant, if the request was resolved to one
+func FromContext(ctx context.Context) (Tenant, bool) {
+	t, ok := ctx.Value(contextKey{}).(Tenant)
+	return t, ok
+}
@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/event"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CommandMonitor creates a client span, child of the operation's context, for
+// every MongoDB command. The command document itself is not recorded since
+// it may hold password hashes and tokens.
+func CommandMonitor() *event.CommandMonitor {
+	var spans sync.Map
+
+	key := func(connectionID string, requestID int64) string {
+		return connectionID + "/" + strconv.FormatInt(requestID, 10)
+	}
+
+	pop := func(connectionID string, requestID int64) (trace.Span, bool) {
+		span, ok := spans.LoadAndDelete(key(connectionID, requestID))
+		if !ok {
+			return nil, false
+		}
+		return span.(trace.Span), true
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			name := e.CommandName
+			collection, _ := e.Command.Lookup(e.CommandName).StringValueOK()
+			if collection != "" {
+				name += " " + collection
+			}
+
+			_, span := Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					semconv.DBSystemMongoDB,
+					semconv.DBNamespace(e.DatabaseName),
+					semconv.DBOperationName(e.CommandName),
+					semconv.DBCollectionName(collection),
+				),
+			)
+			spans.Store(key(e.ConnectionID, e.RequestID), span)
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			if span, ok := pop(e.ConnectionID, e.RequestID); ok {
+				span.End()
+			}
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			if span, ok := pop(e.ConnectionID, e.RequestID); ok {
+				Fail(span, e.Failure)
+				span.End()
+			}
+		},
+	}
+}
@@ -1,59 +1,80 @@
 package utils
 
 import (
+	"context"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"log"
 	"math/big"
-	"net/http"
-	"os"
+	"reflect"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/joho/godotenv"
+	"github.com/kenztech/go-api-starter/apperror"
+	"github.com/kenztech/go-api-starter/i18n"
+	"github.com/kenztech/go-api-starter/models/api"
 )
 
-var (
-	validate  *validator.Validate
-	secretKey []byte
-	otpStore  = make(map[string]string)
-	otpMutex  = sync.Mutex{}
-)
+const otpTTL = 10 * time.Minute
 
-func init() {
-	validate = validator.New()
-	if err := godotenv.Load(); err != nil {
-		log.Printf("Error loading .env file: %v", err)
-	}
-	jwtKey := os.Getenv("JWT_KEY")
-	secretKey = []byte(jwtKey)
+type otpEntry struct {
+	otp       string
+	expiresAt time.Time
 }
 
+var (
+	validate = validator.New()
+	otpStore = make(map[string]otpEntry)
+	otpMutex = sync.Mutex{}
+)
+
 // StoreOTP stores the OTP for the given email.
 func StoreOTP(email, otp string) {
 	otpMutex.Lock()
 	defer otpMutex.Unlock()
 
-	otpStore[email] = otp
-
-	go func() {
-		time.Sleep(10 * time.Minute)
-		otpMutex.Lock()
-		delete(otpStore, email)
-		otpMutex.Unlock()
-	}()
+	otpStore[email] = otpEntry{otp: otp, expiresAt: time.Now().Add(otpTTL)}
 }
 
 func RetrieveOTP(email string) (string, bool) {
 	otpMutex.Lock()
 	defer otpMutex.Unlock()
 
-	otp, exists := otpStore[email]
-	return otp, exists
+	entry, exists := otpStore[email]
+	if !exists || time.Now().After(entry.expiresAt) {
+		return "", false
+	}
+	return entry.otp, true
+}
+
+// SweepOTPs removes expired OTPs every minute until ctx is cancelled. It is
+// meant to run as a background worker.
+func SweepOTPs(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			otpMutex.Lock()
+			for email, entry := range otpStore {
+				if now.After(entry.expiresAt) {
+					delete(otpStore, email)
+				}
+			}
+			otpMutex.Unlock()
+		}
+	}
 }
 
 type Claims struct {
@@ -61,33 +82,78 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-func ValidateStruct(w http.ResponseWriter, request interface{}) bool {
-	if err := validate.Struct(request); err != nil {
-		var errs []string
-		for _, err := range err.(validator.ValidationErrors) {
-			field := strings.ToLower(err.Field())
-			var message string
-			switch err.Tag() {
-			case "required":
-				message = field + " is required"
-			case "email":
-				message = field + " must be a valid email address"
-			case "min":
-				message = field + " must be at least " + err.Param() + " characters long"
-			default:
-				message = field + " is not valid"
-			}
+func init() {
+	// Report fields by their JSON name, which is what clients send.
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			return strings.ToLower(field.Name)
+		}
+		return name
+	})
+
+	// slug: a lower case DNS label, so it can name a subdomain
+	validate.RegisterValidation("slug", func(fl validator.FieldLevel) bool {
+		return slugPattern.MatchString(fl.Field().String())
+	})
+}
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// Validate validates the struct and returns an AppError with one field
+// error per failed rule, or nil.
+func Validate(request interface{}) error {
+	if errs := ValidationErrors(request); len(errs) > 0 {
+		return apperror.Validation(errs)
+	}
+	return nil
+}
+
+// ValidationMessages validates the struct and returns a readable message for
+// every failed rule, so the same checks can be reused outside HTTP handlers.
+func ValidationMessages(request interface{}) []string {
+	var messages []string
+	for _, err := range ValidationErrors(request) {
+		messages = append(messages, err.Message)
+	}
+	return messages
+}
+
+// ValidationErrors validates the struct and describes every failed rule
+func ValidationErrors(request interface{}) []api.FieldError {
+	err := validate.Struct(request)
+	if err == nil {
+		return nil
+	}
+
+	return fieldErrors(err.(validator.ValidationErrors))
+}
 
-			errs = append(errs, message)
+func fieldErrors(invalid validator.ValidationErrors) []api.FieldError {
+	var errs []api.FieldError
+	for _, err := range invalid {
+		field, param := err.Field(), err.Param()
+		if err.Tag() == "required_without" {
+			// The param is the other field's Go name; clients know its JSON
+			// name, which is the lower case one for these fields.
+			param = strings.ToLower(param)
+		}
+		message := i18n.RuleMessage(i18n.Default(), err.Tag(), field, param)
+		if message == "" {
+			message = field + " is not valid"
 		}
 
-		SendError(w, strings.Join(errs, ", "), http.StatusBadRequest)
-		return false
+		errs = append(errs, api.FieldError{
+			Field:   field,
+			Rule:    err.Tag(),
+			Param:   param,
+			Message: message,
+		})
 	}
-	return true
+	return errs
 }
 
-func GenerateToken(email string, expiration time.Duration) (string, error) {
+func (t *TokenManager) GenerateToken(email string, expiration time.Duration) (string, error) {
 	expirationTime := time.Now().Add(expiration)
 
 	claims := &Claims{
@@ -99,21 +165,21 @@ func GenerateToken(email string, expiration time.Duration) (string, error) {
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	tokenString, err := token.SignedString(secretKey)
+	tokenString, err := token.SignedString(t.secret)
 	if err != nil {
 		return "", err
 	}
 	return tokenString, nil
 }
 
-func ValidateToken(tokenString string) (string, bool) {
+func (t *TokenManager) ValidateToken(tokenString string) (string, bool) {
 	claims := &Claims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return secretKey, nil
+		return t.secret, nil
 	})
 
 	if err != nil || !token.Valid {
@@ -123,7 +189,7 @@ func ValidateToken(tokenString string) (string, bool) {
 	return claims.Email, true
 }
 
-func GenerateTokenWithOTP(email, otp string) (string, error) {
+func (t *TokenManager) GenerateTokenWithOTP(email, otp string) (string, error) {
 	claims := jwt.MapClaims{
 		"email": email,
 		"otp":   otp,
@@ -131,15 +197,15 @@ func GenerateTokenWithOTP(email, otp string) (string, error) {
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secretKey)
+	return countIssued("otp")(token.SignedString(t.secret))
 }
 
-func ValidateOTPToken(tokenStr, otp string) (string, error) {
+func (t *TokenManager) ValidateOTPToken(tokenStr, otp string) (string, error) {
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
 		}
-		return secretKey, nil
+		return t.secret, nil
 	})
 
 	if err != nil {
@@ -155,43 +221,82 @@ func ValidateOTPToken(tokenStr, otp string) (string, error) {
 			return "", errors.New("token expired")
 		}
 
-		return claims["email"].(string), nil
+		email, ok := claims["email"].(string)
+		if !ok {
+			return "", errors.New("missing email claim")
+		}
+		return email, nil
 	}
 
 	return "", errors.New("invalid token")
 }
 
-func GenerateResetToken(email string) (string, error) {
+// ResetToken is a validated password reset token
+type ResetToken struct {
+	Email string
+	stamp string
+}
+
+// ResetTokenMatches reports whether the reset token was issued for the
+// password hash, which makes tokens single use: the reset changes the hash.
+func (t *TokenManager) ResetTokenMatches(token ResetToken, passwordHash string) bool {
+	return subtle.ConstantTimeCompare([]byte(token.stamp), []byte(t.passwordStamp(passwordHash))) == 1
+}
+
+// GenerateResetToken creates a password reset token for the user with the
+// given password hash
+func (t *TokenManager) GenerateResetToken(email, passwordHash string) (string, error) {
 	claims := jwt.MapClaims{
+		"typ":   "reset",
 		"email": email,
-		"exp":   time.Now().Add(15 * time.Minute).Unix(),
+		"pwd":   t.passwordStamp(passwordHash),
+		"exp":   time.Now().Add(t.resetTTL).Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secretKey)
+	return countIssued("reset")(token.SignedString(t.secret))
 }
 
-func ValidateResetToken(tokenStr string) (string, error) {
+// ValidateResetToken checks a reset token. Other tokens signed with the same
+// secret, such as sessions, are rejected. The caller must then check the
+// token against the user's password hash with ResetTokenMatches.
+func (t *TokenManager) ValidateResetToken(tokenStr string) (ResetToken, error) {
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
 		}
-		return secretKey, nil
+		return t.secret, nil
 	})
-
 	if err != nil {
-		return "", err
+		return ResetToken{}, err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		if exp, ok := claims["exp"].(float64); ok && time.Unix(int64(exp), 0).Before(time.Now()) {
-			return "", errors.New("token expired")
-		}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return ResetToken{}, errors.New("invalid token")
+	}
+	if typ, _ := claims["typ"].(string); typ != "reset" {
+		return ResetToken{}, errors.New("not a reset token")
+	}
 
-		return claims["email"].(string), nil
+	email, ok := claims["email"].(string)
+	if !ok || email == "" {
+		return ResetToken{}, errors.New("missing email claim")
+	}
+	stamp, ok := claims["pwd"].(string)
+	if !ok || stamp == "" {
+		return ResetToken{}, errors.New("missing password claim")
 	}
 
-	return "", errors.New("invalid token")
+	return ResetToken{Email: email, stamp: stamp}, nil
+}
+
+// passwordStamp identifies a password hash without revealing it, since token
+// claims can be read by anyone holding the token
+func (t *TokenManager) passwordStamp(passwordHash string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte("reset:" + passwordHash))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
 }
 
 func GenerateOTP() string {
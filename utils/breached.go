@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// hashPrefixLength matches the k-anonymity range size used by breach corpora.
+const hashPrefixLength = 5
+
+// BreachedPasswords is an offline set of SHA-1 hashes of breached passwords,
+// bucketed by hash prefix the same way k-anonymity range APIs are.
+type BreachedPasswords struct {
+	ranges map[string]map[string]struct{}
+}
+
+// LoadBreachedPasswords reads a file with one uppercase or lowercase SHA-1
+// hash per line, optionally followed by ":count". Blank lines and lines
+// starting with "#" are ignored.
+func LoadBreachedPasswords(path string) (*BreachedPasswords, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer file.Close()
+
+	breached := &BreachedPasswords{ranges: make(map[string]map[string]struct{})}
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(text, ":")
+		hash = strings.ToUpper(hash)
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("invalid hash on line %d of %s", line, path)
+		}
+		breached.add(hash)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+
+	return breached, nil
+}
+
+// Contains reports whether the password's hash is in the list
+func (b *BreachedPasswords) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	suffixes, ok := b.ranges[hash[:hashPrefixLength]]
+	if !ok {
+		return false
+	}
+	_, found := suffixes[hash[hashPrefixLength:]]
+	return found
+}
+
+func (b *BreachedPasswords) add(hash string) {
+	prefix, suffix := hash[:hashPrefixLength], hash[hashPrefixLength:]
+	if b.ranges[prefix] == nil {
+		b.ranges[prefix] = make(map[string]struct{})
+	}
+	b.ranges[prefix][suffix] = struct{}{}
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/kenztech/go-api-starter/apperror"
+)
+
+// Tagged is implemented by response bodies that have an entity tag. JSON
+// sends it in the ETag header and answers a matching If-None-Match with 304.
+type Tagged interface {
+	ETag() string
+}
+
+// CheckIfMatch makes a change conditional on the resource's current entity
+// tag. It fails with a 428 when the client sent no If-Match header and with a
+// 412 when the header doesn't match, meaning the client's copy is outdated.
+func CheckIfMatch(r *http.Request, etag string) error {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return apperror.ErrPreconditionRequired
+	}
+	// If-Match uses the strong comparison: weak tags never match.
+	if !matchETag(header, etag, false) {
+		return apperror.ErrPreconditionFailed
+	}
+	return nil
+}
+
+// notModified reports whether the If-None-Match header matches etag
+func notModified(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	return header != "" && matchETag(header, etag, true)
+}
+
+// matchETag reports whether a comma separated list of entity tags, or "*",
+// contains etag
+func matchETag(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// sendTagged sends the entity tag of the response and reports whether the
+// client's cached copy is still current, in which case a 304 was written
+func sendTagged(w http.ResponseWriter, r *http.Request, response Tagged) bool {
+	etag := response.ETag()
+	w.Header().Set("ETag", etag)
+	// Tagged responses are private and must be revalidated before reuse.
+	w.Header().Set("Cache-Control", "private, no-cache")
+
+	if (r.Method == http.MethodGet || r.Method == http.MethodHead) && notModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/kenztech/go-api-starter/apperror"
+	"github.com/kenztech/go-api-starter/i18n"
+	"github.com/kenztech/go-api-starter/logging"
+	"github.com/kenztech/go-api-starter/models/api"
+	"github.com/kenztech/go-api-starter/requestid"
+)
+
+const problemContentType = "application/problem+json"
+
+// WriteError renders err for the client. AppErrors are sent with their
+// status, code and field errors, validator errors as field errors, and
+// the errors apperror.From knows are mapped to their status. Anything else is
+// logged, with the stack where it was wrapped by apperror.Internal if it was,
+// and sent as a generic internal error, so internal details never reach the
+// client. Messages are translated to the language of
+// the Accept-Language header, and clients accepting application/problem+json
+// get an RFC 7807 body.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	var invalid validator.ValidationErrors
+	if errors.As(err, &invalid) {
+		err = apperror.Validation(fieldErrors(invalid))
+	}
+	appErr := apperror.From(err)
+
+	logger := logging.FromContext(r.Context())
+	switch {
+	case appErr.Code == apperror.CodeRequestCanceled:
+		logger.Info("Request canceled by the client")
+	case appErr.Status >= 500:
+		attrs := []interface{}{"code", appErr.Code, "error", appErr.Err}
+		if stack := appErr.Stack(); stack != nil {
+			attrs = append(attrs, "stack", stack)
+		}
+		logger.Error(appErr.Message, attrs...)
+	}
+
+	trans := i18n.FromRequest(r)
+	appErr = i18n.Translate(trans, appErr)
+	w.Header().Set("Content-Language", trans.Locale())
+	w.Header().Add("Vary", "Accept-Language")
+
+	id := w.Header().Get(requestid.Header)
+	if acceptsProblem(r) {
+		w.Header().Set("Content-Type", problemContentType)
+		w.WriteHeader(appErr.Status)
+		json.NewEncoder(w).Encode(api.Problem{
+			Type:      "about:blank",
+			Title:     http.StatusText(appErr.Status),
+			Status:    appErr.Status,
+			Detail:    appErr.Message,
+			Instance:  r.URL.Path,
+			Code:      string(appErr.Code),
+			Errors:    appErr.Fields,
+			RequestID: id,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(appErr.Status)
+	json.NewEncoder(w).Encode(api.ErrorResponse{
+		Success:   false,
+		Message:   appErr.Message,
+		Code:      string(appErr.Code),
+		Errors:    appErr.Fields,
+		RequestID: id,
+	})
+}
+
+// acceptsProblem reports whether the client explicitly asked for problem
+// details. Plain JSON stays the default for existing clients.
+func acceptsProblem(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && mediaType == problemContentType {
+			return true
+		}
+	}
+	return false
+}
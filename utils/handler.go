@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/kenztech/go-api-starter/apperror"
+	"github.com/kenztech/go-api-starter/logging"
+)
+
+// HandlerFunc is an HTTP handler that returns its error instead of writing
+// it. The error is rendered by WriteError, so a handler must not have
+// written anything when it returns one.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+func (f HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := f(w, r); err != nil {
+		WriteError(w, r, err)
+	}
+}
+
+// Handle adapts an error-returning handler for the router
+func Handle(f HandlerFunc) http.HandlerFunc {
+	return handle(funcName(f), f)
+}
+
+// handle serves f and adds the name of the handler to the request's logs
+// when it fails, so errors can be traced back to it
+func handle(name string, f HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := f(w, r); err != nil {
+			logging.AddAttrs(r.Context(), slog.String("handler", name))
+			WriteError(w, r, err)
+		}
+	}
+}
+
+// funcName is the package qualified name of a function, like
+// "handlers.(*AuthHandler).Login"
+func funcName(f any) string {
+	name := runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+	name = strings.TrimSuffix(name, "-fm")
+	return name[strings.LastIndex(name, "/")+1:]
+}
+
+// JSON adapts a handler that returns its response body, written as JSON
+// with the given status when there is no error. Tagged bodies support
+// conditional GET requests.
+func JSON[T any](status int, f func(r *http.Request) (T, error)) http.HandlerFunc {
+	return handle(funcName(f), func(w http.ResponseWriter, r *http.Request) error {
+		response, err := f(r)
+		if err != nil {
+			return err
+		}
+		if tagged, ok := any(response).(Tagged); ok && sendTagged(w, r, tagged) {
+			return nil
+		}
+		SendJSON(w, status, response)
+		return nil
+	})
+}
+
+// DecodeJSON decodes the request body into v and validates it
+func DecodeJSON(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return apperror.ErrInvalidBody.Wrap(err)
+	}
+	return Validate(v)
+}
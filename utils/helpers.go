@@ -1,103 +1,160 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"net"
 	"net/http"
 	"net/smtp"
+	"strconv"
 
 	"github.com/jordan-wright/email"
+	"github.com/kenztech/go-api-starter/apperror"
+	"github.com/kenztech/go-api-starter/logging"
+	"github.com/kenztech/go-api-starter/metrics"
 	"github.com/kenztech/go-api-starter/models/api"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/kenztech/go-api-starter/requestid"
+	"github.com/kenztech/go-api-starter/tracing"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/gomail.v2"
 )
 
+// EmailSender sends emails through an SMTP server. When SMTPServer is
+// empty, emails are only logged, which is handy for local development.
 type EmailSender struct {
 	SMTPPort   int
 	SMTPServer string
 	Username   string
 	Password   string
+	From       string
 }
 
-func SendMail(to, subject, message string) error {
-	log.Println("Creating email message...")
+func (s *EmailSender) SendMail(ctx context.Context, to, subject, message string) (err error) {
+	ctx, span := tracing.Start(ctx, "SendMail", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() {
+		if err != nil {
+			tracing.Fail(span, err)
+		}
+		span.End()
+	}()
+
+	if s.SMTPServer == "" {
+		return s.SendEmail(ctx, to, subject, message)
+	}
 
 	// Create a new email message
 	msg := gomail.NewMessage()
-	msg.SetHeader("From", "Zemen Innovation<gxforce430@gmail.com>")
+	msg.SetHeader("From", s.From)
 	msg.SetHeader("To", to)
 	msg.SetHeader("Subject", subject)
 	msg.SetBody("text/plain", message)
+	if id := requestid.FromContext(ctx); id != "" {
+		msg.SetHeader(requestid.Header, id)
+	}
 
 	// Set up the SMTP dialer
-	dialer := gomail.NewDialer("smtp.gmail.com", 587, "gxforce430@gmail.com", "odte rbqr swps ktpm")
+	dialer := gomail.NewDialer(s.SMTPServer, s.SMTPPort, s.Username, s.Password)
 
 	// Attempt to send the email
-	log.Println("Attempting to send email...")
-	err := dialer.DialAndSend(msg)
+	err = dialer.DialAndSend(msg)
+	metrics.EmailResult(err)
 	if err != nil {
-		log.Printf("Failed to send email: %v", err)
+		logging.FromContext(ctx).Error("Failed to send email", "to", to, "subject", subject, "error", err)
 		return err
 	}
 
-	log.Println("Email sent successfully!")
+	logging.FromContext(ctx).Info("Email sent successfully!", "to", to, "subject", subject)
 	return nil
 }
 
+// SendError writes an error with the generic code of its status. Handlers
+// should prefer WriteError, which carries a specific code.
 func SendError(w http.ResponseWriter, message string, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	response := api.ErrorResponse{
 		Success: false,
 		Message: message,
+		Code:    string(apperror.CodeForStatus(statusCode)),
+		// Set by the RequestID middleware, so support can trace the error.
+		RequestID: w.Header().Get(requestid.Header),
 	}
 	json.NewEncoder(w).Encode(response)
 }
 
 func SendJSON(w http.ResponseWriter, statusCode int, data interface{}) {
-	w.WriteHeader(statusCode)
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
 	if err := json.NewEncoder(w).Encode(data); err != nil {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 	}
 }
 
-func SendEmail(to, subject, body string) error {
-	log.Printf("Simulating sending email...\nTo: %s\nSubject: %s\nBody: %s\n", to, subject, body)
-	fmt.Println("Email simulated successfully")
+// Ping checks that the SMTP server accepts connections
+func (s *EmailSender) Ping(ctx context.Context) error {
+	if s.SMTPServer == "" {
+		return nil
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(s.SMTPServer, strconv.Itoa(s.SMTPPort)))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (s *EmailSender) SendEmail(ctx context.Context, to, subject, body string) error {
+	// Mail is not configured: only note the email. The body stays out of the
+	// logs since it can carry secrets such as password reset links.
+	logging.FromContext(ctx).Info("Simulated sending email", "to", to, "subject", subject)
 	return nil
 }
 
-func SendHTMLEmail(to, subject, htmlContent string, cc []string, attachments ...string) error {
+func (s *EmailSender) SendHTMLEmail(ctx context.Context, to, subject, htmlContent string, cc []string, attachments ...string) (err error) {
+	ctx, span := tracing.Start(ctx, "SendHTMLEmail", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() {
+		if err != nil {
+			tracing.Fail(span, err)
+		}
+		span.End()
+	}()
+
+	if s.SMTPServer == "" {
+		return s.SendEmail(ctx, to, subject, htmlContent)
+	}
+
 	e := email.NewEmail()
-	e.From = "Zemen Innovation <gxforce430@gmail.com>"
+	e.From = s.From
 	e.To = []string{to}
 	e.Subject = subject
 	e.HTML = []byte(htmlContent)
+	if id := requestid.FromContext(ctx); id != "" {
+		e.Headers.Set(requestid.Header, id)
+	}
 
 	if cc != nil {
 		e.Cc = cc
 	}
 
-	err := e.Send("smtp.gmail.com:587", smtp.PlainAuth("", "gxforce430@gmail.com", "fuvw ylpy xqgn iish", "smtp.gmail.com"))
+	addr := net.JoinHostPort(s.SMTPServer, strconv.Itoa(s.SMTPPort))
+	err = e.Send(addr, smtp.PlainAuth("", s.Username, s.Password, s.SMTPServer))
+	metrics.EmailResult(err)
 	if err != nil {
 		return fmt.Errorf("failed to send email: %w", err)
 	}
 	return nil
 }
 
-// HashPassword hashes a plain text password using bcrypt.
+// HashPassword hashes a plain text password using the configured PasswordHasher.
 func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
-	}
-	return string(hashedPassword), nil
+	return passwordHasher.Hash(password)
 }
 
 // ComparePassword checks if the provided password matches the hashed password.
+// The algorithm is detected from the hash, so older bcrypt hashes keep working
+// after switching to argon2id.
 func ComparePassword(hashedPassword, password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
-	return err == nil
+	return hasherFor(hashedPassword).Compare(hashedPassword, password)
 }
@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kenztech/go-api-starter/apperror"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// IDs are ObjectIDs, exposed to clients, tokens and URLs as 24 character hex
+// strings. ObjectIDs marshal to that form in JSON on their own.
+
+// ParseID parses a hex ID, rejecting malformed ones with a 400
+func ParseID(s string) (bson.ObjectID, error) {
+	id, err := bson.ObjectIDFromHex(s)
+	if err != nil {
+		return bson.NilObjectID, apperror.ErrInvalidID.Wrap(err)
+	}
+	return id, nil
+}
+
+// URLParamID parses the named URL parameter as an ID
+func URLParamID(r *http.Request, name string) (bson.ObjectID, error) {
+	return ParseID(chi.URLParam(r, name))
+}
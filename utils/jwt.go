@@ -2,29 +2,55 @@ package utils
 
 import (
 	"context"
-	"os"
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/kenztech/go-api-starter/metrics"
 )
 
-var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
-
 type contextKey string
 
 const UserContextKey contextKey = "userData"
 
 type UserData struct {
-	ID    uint
+	// ID is the user's hex ID. Tokens issued before IDs were added to them
+	// lack it until the authenticator fills it in from the user.
+	ID    string
 	Email string
 	Role  string
+	// ImpersonatorEmail is set when an admin is acting as this user.
+	ImpersonatorEmail string
+	// MustChangePassword is set until the user replaces a generated password.
+	MustChangePassword bool
+	// IssuedAt is when the token was issued, used to honor revocations.
+	IssuedAt time.Time
+	// issuedAtMillis is set when IssuedAt has millisecond precision. Older
+	// tokens only carry seconds.
+	issuedAtMillis bool
+	// OrgID is the hex ID of the organization picked at login, if any.
+	OrgID string
 }
 
-func SetUserDataInContext(ctx context.Context, email, role string) context.Context {
-	userData := UserData{
-		Email: email,
-		Role:  role,
+// IssuedBefore reports whether the token was issued before t. For tokens
+// with second precision, the whole second of t counts as before it, so
+// revocations can't be outlived by a token issued in the same second.
+func (u UserData) IssuedBefore(t time.Time) bool {
+	if u.IssuedAt.IsZero() {
+		return true
+	}
+	if !u.issuedAtMillis {
+		return !u.IssuedAt.After(t.Truncate(time.Second))
 	}
+	return u.IssuedAt.Before(t.Truncate(time.Millisecond))
+}
+
+// IsImpersonated reports whether the request is made by an admin impersonating the user.
+func (u UserData) IsImpersonated() bool {
+	return u.ImpersonatorEmail != ""
+}
+
+func SetUserDataInContext(ctx context.Context, userData UserData) context.Context {
 	return context.WithValue(ctx, UserContextKey, userData)
 }
 
@@ -33,8 +59,28 @@ func GetUserDataFromContext(ctx context.Context) (UserData, bool) {
 	return userData, ok
 }
 
+// TokenManager signs and verifies every token issued by the API
+type TokenManager struct {
+	secret     []byte
+	sessionTTL time.Duration
+	resetTTL   time.Duration
+}
+
+func NewTokenManager(secret string, sessionTTL, resetTTL time.Duration) *TokenManager {
+	return &TokenManager{
+		secret:     []byte(secret),
+		sessionTTL: sessionTTL,
+		resetTTL:   resetTTL,
+	}
+}
+
+// SessionTTL is the lifetime of refresh tokens
+func (t *TokenManager) SessionTTL() time.Duration {
+	return t.sessionTTL
+}
+
 // GenerateAccessToken creates a JWT including user ID, email, and role
-func GenerateAccessToken(id uint, email, role string) (string, error) {
+func (t *TokenManager) GenerateAccessToken(id, email, role string) (string, error) {
 	claims := jwt.MapClaims{
 		"authorized": true,
 		"id":         id,
@@ -44,49 +90,113 @@ func GenerateAccessToken(id uint, email, role string) (string, error) {
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return countIssued("access")(token.SignedString(t.secret))
+}
+
+// GenerateRefreshToken creates a refresh token with user ID, email, and role.
+// orgID, when set, is the organization the session acts for by default.
+func (t *TokenManager) GenerateRefreshToken(id, email, role, orgID string, mustChangePassword bool) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub":    id,
+		"email":  email,
+		"role":   role,
+		"iat":    now.Unix(),
+		"iat_ms": now.UnixMilli(),
+		"exp":    now.Add(t.sessionTTL).Unix(),
+	}
+	if orgID != "" {
+		claims["org"] = orgID
+	}
+	if mustChangePassword {
+		claims["pwd_change"] = true
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return countIssued("session")(token.SignedString(t.secret))
 }
 
-// GenerateRefreshToken creates a refresh token with user ID, email, and role
-func GenerateRefreshToken(email, role string) (string, error) {
+// GenerateImpersonationToken creates a short-lived token for the impersonated user.
+// The real actor is carried in the "act" claim (RFC 8693) so it can be audited.
+func (t *TokenManager) GenerateImpersonationToken(id, email, role, actorEmail string, ttl time.Duration) (string, error) {
+	now := time.Now()
 	claims := jwt.MapClaims{
-		"email": email,
-		"role":  role,
-		"exp":   time.Now().Add(24 * time.Hour).Unix(),
+		"sub":    id,
+		"email":  email,
+		"role":   role,
+		"act":    map[string]interface{}{"sub": actorEmail},
+		"iat":    now.Unix(),
+		"iat_ms": now.UnixMilli(),
+		"exp":    now.Add(ttl).Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return countIssued("impersonation")(token.SignedString(t.secret))
 }
 
 // ValidateTheToken parses and validates a JWT
-func ValidateTheToken(tokenString string) (*jwt.Token, error) {
+func (t *TokenManager) ValidateTheToken(tokenString string) (*jwt.Token, error) {
 	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return t.secret, nil
 	})
 }
 
 // VerifyRefreshToken extracts user details from the refresh token
-func VerifyRefreshToken(tokenString string) (string, string, error) {
-	token, err := ValidateTheToken(tokenString)
-	if err != nil || !token.Valid {
-		return "", "", err
+func (t *TokenManager) VerifyRefreshToken(tokenString string) (UserData, error) {
+	token, err := t.ValidateTheToken(tokenString)
+	if err != nil {
+		return UserData{}, err
+	}
+	if !token.Valid {
+		return UserData{}, errors.New("invalid token")
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return "", "", err
+		return UserData{}, errors.New("invalid token claims")
 	}
 
 	email, ok := claims["email"].(string)
 	if !ok {
-		return "", "", err
+		return UserData{}, errors.New("missing email claim")
 	}
 
 	role, ok := claims["role"].(string)
 	if !ok {
-		return "", "", err
+		return UserData{}, errors.New("missing role claim")
+	}
+
+	userData := UserData{Email: email, Role: role}
+	userData.ID, _ = claims["sub"].(string)
+	userData.OrgID, _ = claims["org"].(string)
+	userData.MustChangePassword, _ = claims["pwd_change"].(bool)
+	if ms, ok := claims["iat_ms"].(float64); ok {
+		userData.IssuedAt = time.UnixMilli(int64(ms))
+		userData.issuedAtMillis = true
+	} else if iat, ok := claims["iat"].(float64); ok {
+		userData.IssuedAt = time.Unix(int64(iat), 0)
+	}
+	if act, ok := claims["act"].(map[string]interface{}); ok {
+		actor, ok := act["sub"].(string)
+		if !ok || actor == "" {
+			return UserData{}, errors.New("invalid actor claim")
+		}
+		userData.ImpersonatorEmail = actor
 	}
 
-	return email, role, nil
+	return userData, nil
+}
+
+// countIssued counts successfully signed tokens of the given type. It wraps
+// the result of SignedString, which both JWT versions in use share.
+func countIssued(tokenType string) func(string, error) (string, error) {
+	return func(token string, err error) (string, error) {
+		if err == nil {
+			metrics.TokensIssued.WithLabelValues(tokenType).Inc()
+		}
+		return token, err
+	}
 }
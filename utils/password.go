@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/kenztech/go-api-starter/apperror"
+	"github.com/kenztech/go-api-starter/i18n"
+	"github.com/kenztech/go-api-starter/models/api"
+)
+
+// PasswordPolicy describes the rules a new password has to satisfy
+type PasswordPolicy struct {
+	MinLength int
+	// MaxBytes caps the length in bytes; bcrypt silently ignores anything
+	// past 72 bytes, so longer passwords are rejected instead.
+	MaxBytes      int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// HistorySize is the number of previous passwords that cannot be reused.
+	HistorySize int
+	// DisallowIdentifiers rejects passwords containing the email or username.
+	DisallowIdentifiers bool
+	// Breached is an optional offline list of known breached passwords.
+	Breached *BreachedPasswords
+}
+
+// Check returns a message for every rule the password violates. Identifiers
+// are the user's email and username, which must not appear in the password.
+func (p *PasswordPolicy) Check(password string, identifiers ...string) []string {
+	var errs []string
+	for _, v := range p.Violations(password, identifiers...) {
+		errs = append(errs, v.Message)
+	}
+	return errs
+}
+
+// Violations returns every rule the password violates as field errors on
+// the "password" field.
+func (p *PasswordPolicy) Violations(password string, identifiers ...string) []api.FieldError {
+	var errs []api.FieldError
+	violate := func(rule, param string) {
+		message := i18n.RuleMessage(i18n.Default(), rule, "password", param)
+		errs = append(errs, api.FieldError{Field: "password", Rule: rule, Param: param, Message: message})
+	}
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		violate("min", strconv.Itoa(p.MinLength))
+	}
+	if len(password) > p.MaxBytes {
+		violate("max_bytes", strconv.Itoa(p.MaxBytes))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, c := range password {
+		switch {
+		case unicode.IsUpper(c):
+			hasUpper = true
+		case unicode.IsLower(c):
+			hasLower = true
+		case unicode.IsDigit(c):
+			hasDigit = true
+		case unicode.IsPunct(c) || unicode.IsSymbol(c):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		violate("uppercase", "")
+	}
+	if p.RequireLower && !hasLower {
+		violate("lowercase", "")
+	}
+	if p.RequireDigit && !hasDigit {
+		violate("digit", "")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violate("symbol", "")
+	}
+
+	if p.DisallowIdentifiers {
+		lower := strings.ToLower(password)
+	identifierLoop:
+		for _, identifier := range identifiers {
+			for _, part := range identifierParts(identifier) {
+				if strings.Contains(lower, part) {
+					violate("no_identifiers", "")
+					break identifierLoop
+				}
+			}
+		}
+	}
+
+	if p.Breached != nil && p.Breached.Contains(password) {
+		violate("not_breached", "")
+	}
+
+	return errs
+}
+
+// IsReused reports whether the password matches the current hash or one of
+// the last HistorySize hashes.
+func (p *PasswordPolicy) IsReused(password, current string, history []string) bool {
+	if current != "" && ComparePassword(current, password) {
+		return true
+	}
+	for _, hash := range p.trimHistory(history) {
+		if ComparePassword(hash, password) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextHistory returns the history to store once the current hash is replaced.
+func (p *PasswordPolicy) NextHistory(current string, history []string) []string {
+	if p.HistorySize <= 0 {
+		return nil
+	}
+	if current != "" {
+		history = append([]string{current}, history...)
+	}
+	return p.trimHistory(history)
+}
+
+func (p *PasswordPolicy) trimHistory(history []string) []string {
+	if len(history) > p.HistorySize {
+		return history[:p.HistorySize]
+	}
+	return history
+}
+
+// Validate checks the password against the policy and returns a validation
+// error listing every violation, or nil.
+func (p *PasswordPolicy) Validate(password string, identifiers ...string) error {
+	if errs := p.Violations(password, identifiers...); len(errs) > 0 {
+		return apperror.Validation(errs)
+	}
+	return nil
+}
+
+// identifierParts splits an email into its local part and returns usable
+// lowercase substrings, ignoring ones too short to be meaningful.
+func identifierParts(identifier string) []string {
+	identifier = strings.ToLower(strings.TrimSpace(identifier))
+	if local, _, found := strings.Cut(identifier, "@"); found {
+		identifier = local
+	}
+	if len(identifier) < 3 {
+		return nil
+	}
+	return []string{identifier}
+}